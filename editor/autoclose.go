@@ -0,0 +1,69 @@
+package editor
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Pairs closed as their opener is typed with autoclose on, opener then
+// closer.
+const AUTOCLOSE_PAIRS = "()[]{}\"\"''``"
+
+// Whether chars holds an opener at i immediately followed by its closer.
+func isEmptyPair(chars string, i int) bool {
+	if i < 0 || i+1 >= len(chars) {
+		return false
+	}
+	for j := 0; j < len(AUTOCLOSE_PAIRS); j += 2 {
+		if chars[i] == AUTOCLOSE_PAIRS[j] && chars[i+1] == AUTOCLOSE_PAIRS[j+1] {
+			return true
+		}
+	}
+	return false
+}
+
+// Type r, with autoclose adding the closer of an opener after the cursor,
+// and typing a closer over the same one already there. Quotes are only
+// paired after a non-word character, so "don't" stays one quote.
+func (ed *Editor) typeChar(r rune) {
+	if !ed.cfg.autoClose || ed.overwrite || r >= utf8.RuneSelf || !ed.checkWritable() {
+		ed.insertChar(r)
+		return
+	}
+	chars, c := "", byte(r)
+	if ed.numRows() > 0 {
+		chars = ed.rows[ed.cy].chars
+	}
+	if ed.cx < len(chars) && chars[ed.cx] == c && isCloser(c) {
+		ed.cx++
+		return
+	}
+	i := strings.IndexByte(AUTOCLOSE_PAIRS, c)
+	ed.insertChar(r)
+	if i >= 0 && i%2 == 0 && (AUTOCLOSE_PAIRS[i+1] != c || ed.cx == 1 || ed.isSeparator(chars[ed.cx-2])) {
+		ed.insertChar(rune(AUTOCLOSE_PAIRS[i+1]))
+		ed.cx--
+	}
+}
+
+// Whether c closes one of AUTOCLOSE_PAIRS.
+func isCloser(c byte) bool {
+	for j := 1; j < len(AUTOCLOSE_PAIRS); j += 2 {
+		if AUTOCLOSE_PAIRS[j] == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Delete both sides of an empty pair around the cursor, e.g. the () of a
+// call just typed, for Backspace and Delete. Return false when the cursor
+// isn't between one, or autoclose is off.
+func (ed *Editor) deletePair() bool {
+	if !ed.cfg.autoClose || ed.numRows() == 0 || !isEmptyPair(ed.rows[ed.cy].chars, ed.cx-1) {
+		return false
+	}
+	ed.rowDelChars(ed.cy, ed.cx-1, ed.cx+1)
+	ed.cx--
+	return true
+}
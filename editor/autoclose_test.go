@@ -0,0 +1,52 @@
+package editor
+
+import "testing"
+
+const deleteKey = "\x1b[3~"
+
+func TestAutoClose(t *testing.T) {
+	tests := []struct {
+		name, keys, want string
+	}{
+		{"closes a bracket", "f(", "f()\n"},
+		{"types over the closer", "f(a)", "f(a)\n"},
+		{"closes quotes", "x = \"a\"", "x = \"a\"\n"},
+		{"leaves apostrophes", "don't", "don't\n"},
+		{"backspace deletes an empty pair", "f(\x7f", "f\n"},
+		{"backspace deletes empty quotes", "\"\x7f", "\n"},
+		{"delete deletes an empty pair", "f(" + deleteKey, "f\n"},
+		{"nested pairs one at a time", "f([\x7f", "f()\n"},
+		{"nested pairs all the way", "f([\x7f\x7f", "f\n"},
+		{"nested pairs with delete", "f({" + deleteKey + deleteKey, "f\n"},
+		{"not an empty pair", "f(a\x7f", "f()\n"},
+		{"delete in a non-empty pair", "f(a\x1b[D" + deleteKey, "f()\n"},
+		{"mismatched neighbours", "(\x1b[C[\x1b[D\x1b[D\x1b[C" + deleteKey, "()]\n"},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.autoClose = true
+		ed, _ := runKeys(t, cfg, "", insertKey+tt.keys)
+		if got := ed.Contents(); got != tt.want {
+			t.Errorf("%s: buffer %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAutoCloseOff(t *testing.T) {
+	ed, _ := runKeys(t, DefaultConfig(), "", insertKey+"f(\x7f(")
+	if got := ed.Contents(); got != "f(\n" {
+		t.Errorf("buffer %q, want nothing added without autoclose", got)
+	}
+	ed, _ = runKeys(t, DefaultConfig(), writeTemp(t, "a.txt", "()\n"), "\x1b[C"+deleteKey)
+	if got := ed.Contents(); got != "(\n" {
+		t.Errorf("buffer %q, want Delete to take one character without autoclose", got)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	path := writeTemp(t, "a.txt", "héllo\nworld\n")
+	ed, _ := runKeys(t, DefaultConfig(), path, "\x1b[C"+deleteKey+"\x1b[F"+deleteKey)
+	if got := ed.Contents(); got != "hlloworld\n" {
+		t.Errorf("buffer %q, want the é gone and the lines joined", got)
+	}
+}
@@ -0,0 +1,113 @@
+package editor
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Printed for arguments after --batch that make no sense.
+const BATCH_USAGE = "usage: exa --batch script file, or exa --batch -c command [-c command]... file"
+
+// Run without a terminal, on the arguments after --batch, and return the
+// exit status. The commands come from -c arguments or a script file, "-"
+// for standard input.
+func batchMain(cfg *Config, args []string) int {
+	var commands []string
+	for len(args) >= 2 && args[0] == "-c" {
+		commands, args = append(commands, args[1]), args[2:]
+	}
+	var script io.Reader
+	switch {
+	case len(commands) > 0 && len(args) == 1:
+		script = strings.NewReader(strings.Join(commands, "\n"))
+	case len(commands) == 0 && len(args) == 2 && args[0] == "-":
+		script, args = os.Stdin, args[1:]
+	case len(commands) == 0 && len(args) == 2:
+		f, err := os.Open(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "exa:", err)
+			return 1
+		}
+		defer f.Close()
+		script, args = f, args[1:]
+	default:
+		fmt.Fprintln(os.Stderr, BATCH_USAGE)
+		return 1
+	}
+	code, err := runBatch(cfg, args[0], script, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "exa:", err)
+	}
+	return code
+}
+
+// Run the lines of script as ':' commands on filename, as if typed, and
+// then save the file if they changed it. What a command asks, e.g. whether
+// to overwrite a file, is answered by the lines after it. Blank lines and
+// lines starting with "#" are skipped. The message each command leaves is
+// written to log. Return the exit status: 0 when the script ran to its end
+// and the file was saved or left unchanged, 1 when saving failed, the
+// script quit with unsaved changes or with cq, or a command failed, e.g.
+// was unknown or couldn't read its file, which stops the script without
+// saving.
+func runBatch(cfg *Config, filename string, script io.Reader, log io.Writer) (int, error) {
+	text, err := ioutil.ReadAll(script)
+	if err != nil {
+		return 1, err
+	}
+	// Lines are typed ending in Enter, the last one too.
+	keys := strings.NewReplacer("\r\n", "\r", "\n", "\r").Replace(string(text))
+	if keys != "" && !strings.HasSuffix(keys, "\r") {
+		keys += "\r"
+	}
+	ed := New(cfg, strings.NewReader(keys), ioutil.Discard)
+	ed.size = func() (int, int, error) { return DEFAULT_WIDTH, DEFAULT_HEIGHT, nil }
+	ed.updateSize()
+	// Asking about a big file would take the first line of the script as
+	// the answer, and q means to quit, changes or not.
+	ed.cfg.confirmSize, ed.cfg.quitTimes = 0, 0
+	if err := ed.openArg(filename); err != nil {
+		return 1, err
+	}
+	if ed.browser != nil {
+		return 1, fmt.Errorf("%s is a directory", filename)
+	}
+	for {
+		line, ok := ed.prompt("", nil)
+		if !ok && ed.keys.stopped {
+			// The script ran out, see keyReader.next.
+			break
+		}
+		line = strings.TrimSpace(line)
+		if !ok || line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ed.failed = false
+		if !ed.execCommand(line) {
+			return ed.exitCode, nil
+		}
+		if ed.statusmsg != "" {
+			fmt.Fprintf(log, "exa: %s: %s\n", line, ed.statusmsg)
+		}
+		if ed.failed {
+			// Better not to save what came before it either.
+			return 1, nil
+		}
+		ed.setStatus("")
+	}
+	if !ed.dirty {
+		return ed.exitCode, nil
+	}
+	// The keys ran out, but what saving asks still gets its no, see
+	// keyReader.next. A formatter mustn't be cancelled meanwhile.
+	ed.keys.stopped = false
+	saved := ed.write(nil)
+	fmt.Fprintf(log, "exa: %s\n", ed.statusmsg)
+	if !saved {
+		return 1, nil
+	}
+	return ed.exitCode, nil
+}
@@ -0,0 +1,60 @@
+package editor
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		code   int
+		// What the file holds afterwards.
+		want string
+	}{
+		{"saves the changes", "r more.txt\n", 0, "a\nmore\n"},
+		{"last line without newline", "# insert\n\nr more.txt", 0, "a\nmore\n"},
+		{"nothing to save", "wc\n", 0, "a\n"},
+		{"unknown command", "r more.txt\nfrobnicate\n", 1, "a\n"},
+		{"missing file", "r more.txt\nr missing.txt\n", 1, "a\n"},
+		{"bad usage", "r more.txt\nset\n", 1, "a\n"},
+		{"quits with changes", "r more.txt\ncq\n", 1, "a\n"},
+	}
+	for _, tt := range tests {
+		path := writeTemp(t, "a.txt", "a\n")
+		dir := filepath.Dir(path)
+		if err := ioutil.WriteFile(filepath.Join(dir, "more.txt"), []byte("more\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		script := strings.Replace(tt.script, "more.txt", filepath.Join(dir, "more.txt"), -1)
+		script = strings.Replace(script, "missing.txt", filepath.Join(dir, "missing.txt"), -1)
+		code, err := runBatch(DefaultConfig(), path, strings.NewReader(script), ioutil.Discard)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if code != tt.code {
+			t.Errorf("%s: exit status %d, want %d", tt.name, code, tt.code)
+		}
+		if got, _ := ioutil.ReadFile(path); string(got) != tt.want {
+			t.Errorf("%s: file holds %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRunBatchBigFile(t *testing.T) {
+	// Asking whether to open it used to take the first command as the
+	// answer.
+	cfg := DefaultConfig()
+	cfg.confirmSize = 1
+	path := writeTemp(t, "a.txt", "a\n")
+	code, err := runBatch(cfg, path, strings.NewReader("trim\nfixeol crlf\n"), ioutil.Discard)
+	if err != nil || code != 0 {
+		t.Fatalf("exit status %d, %v, want 0", code, err)
+	}
+	if got, _ := ioutil.ReadFile(path); string(got) != "a\r\n" {
+		t.Errorf("file holds %q, want the line ending changed", got)
+	}
+}
@@ -0,0 +1,131 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// A listing of a directory shown in place of the buffer, to pick a file to
+// open from.
+type dirBrowser struct {
+	dir string
+	// Names in the listing, directories first and ending in "/".
+	entries []string
+	// Entry under the cursor and the first one on screen.
+	cursor, top int
+}
+
+// Show the directory of the current file, or the working directory.
+func (ed *Editor) explore() {
+	dir := "."
+	if ed.filename != "" {
+		dir = filepath.Dir(ed.filename)
+	}
+	ed.browseDir(dir)
+}
+
+// Replace the listing with one of dir.
+func (ed *Editor) browseDir(dir string) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		ed.fail("Can't read %s: %v", dir, err)
+		return
+	}
+	var dirs, files []string
+	for _, fi := range infos {
+		if fi.IsDir() {
+			dirs = append(dirs, fi.Name()+"/")
+		} else {
+			files = append(files, fi.Name())
+		}
+	}
+	sort.Strings(dirs)
+	sort.Strings(files)
+	entries := append([]string{"../"}, dirs...)
+	ed.browser = &dirBrowser{dir: dir, entries: append(entries, files...)}
+}
+
+// Handle a key in the directory listing. Return false to quit.
+func (ed *Editor) browserKeyPress(ch EdKey) bool {
+	b := ed.browser
+	switch ch {
+	case ARW_UP:
+		b.cursor--
+	case ARW_DOWN:
+		b.cursor++
+	case PG_UP:
+		b.cursor -= ed.screenrows
+	case PG_DOWN:
+		b.cursor += ed.screenrows
+	case HOME_KEY:
+		b.cursor = 0
+	case END_KEY:
+		b.cursor = len(b.entries) - 1
+	case '\r':
+		// Keys can come in faster than the frames that keep the cursor
+		// on an entry, see browserScroll.
+		b.cursor = clamp(b.cursor, 0, len(b.entries)-1)
+		name := b.entries[b.cursor]
+		path := filepath.Join(b.dir, name)
+		if name[len(name)-1] == '/' {
+			ed.browseDir(path)
+		} else if ed.edit(path, 0, 0) {
+			ed.browser = nil
+		}
+	case ':':
+		return ed.commandMode()
+	case 0x1b:
+		ed.browser = nil
+	default:
+		if ed.cfg.keymap[ch] == "quit" {
+			return ed.quit()
+		}
+	}
+	return true
+}
+
+// Keep the cursor on an entry and on screen.
+func (ed *Editor) browserScroll() {
+	b := ed.browser
+	if b.cursor >= len(b.entries) {
+		b.cursor = len(b.entries) - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+	if b.cursor < b.top {
+		b.top = b.cursor
+	}
+	if b.cursor >= b.top+ed.screenrows {
+		b.top = b.cursor - ed.screenrows + 1
+	}
+}
+
+func (ed *Editor) drawBrowserRow(ab *bytes.Buffer, y int) {
+	b := ed.browser
+	i := b.top + y
+	if i < len(b.entries) {
+		name := b.entries[i]
+		name = name[:clamp(len(name), 0, ed.width)]
+		if i == b.cursor {
+			name = "\x1b[7m" + name + "\x1b[m"
+		}
+		ab.WriteString(name)
+	} else {
+		ab.WriteString("~")
+	}
+	ab.WriteString("\x1b[K")
+}
+
+// Left and right parts of the status bar for the listing.
+func (ed *Editor) browserStatus() (left, right string) {
+	b := ed.browser
+	left = b.dir + "/"
+	if abs, err := filepath.Abs(b.dir); err == nil {
+		left = abs
+	}
+	return left, fmt.Sprintf("%d/%d", b.cursor+1, len(b.entries))
+}
@@ -0,0 +1,46 @@
+package editor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBrowserEnterAfterKeyBurst(t *testing.T) {
+	dir := filepath.Dir(writeTemp(t, "a.txt", "a\n"))
+	// Up moves the cursor off the first entry, and Enter comes before a
+	// frame could put it back. The first entry is the parent directory.
+	ed, _ := runKeys(t, DefaultConfig(), dir, "\x1b[A\r")
+	if ed.browser == nil || ed.browser.dir != filepath.Dir(dir) {
+		t.Fatalf("browser %+v, want the parent of %s listed", ed.browser, dir)
+	}
+}
+
+func TestOpenDirectoryArgument(t *testing.T) {
+	dir := filepath.Dir(writeTemp(t, "b.txt", "b\n"))
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Listed in the browser, directories first, instead of read as a file.
+	ed, code := runKeys(t, DefaultConfig(), dir, "")
+	if ed.browser == nil || ed.browser.dir != dir || code != 0 {
+		t.Fatalf("browser %+v, exit status %d, want %s listed", ed.browser, code, dir)
+	}
+	if got := strings.Join(ed.browser.entries, " "); got != "../ sub/ a.txt b.txt" {
+		t.Errorf("entries %q", got)
+	}
+	// Enter opens the file picked, or lists the directory.
+	ed, _ = runKeys(t, DefaultConfig(), dir, "\x1b[B\x1b[B\r")
+	if ed.browser != nil || ed.Filename() != filepath.Join(dir, "a.txt") || ed.Contents() != "a\n" {
+		t.Errorf("browser %+v, editing %q holding %q, want a.txt", ed.browser, ed.Filename(), ed.Contents())
+	}
+	ed, _ = runKeys(t, DefaultConfig(), dir, "\x1b[B\r")
+	if ed.browser == nil || ed.browser.dir != filepath.Join(dir, "sub") {
+		t.Errorf("browser %+v, want sub listed", ed.browser)
+	}
+}
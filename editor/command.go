@@ -0,0 +1,464 @@
+package editor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Show a prompt in the message bar and let the user type a line of text.
+// Enter accepts, Escape cancels and returns ok == false. When history is
+// given, up and down arrows walk through its entries.
+func (ed *Editor) prompt(prompt string, history []string) (input string, ok bool) {
+	// Position in history, len(history) is the line being typed.
+	hist := len(history)
+	ed.prompting = true
+	defer func() { ed.prompting = false }()
+	for {
+		ed.setStatus("%s%s", prompt, input)
+		ed.refresh()
+
+		ch := ed.keys.readKey()
+		switch {
+		case ch == STOP:
+			return "", false
+		case ch == '\r':
+			ed.setStatus("")
+			return input, true
+		case ch == 0x1b:
+			ed.setStatus("")
+			return "", false
+		// Backspace is sent as DEL (127) or CTRL+h.
+		case ch == 127, ch == 0x1f&'h':
+			if len(input) > 0 {
+				input = input[:len(input)-1]
+			}
+		// CTRL+w deletes the word before the cursor.
+		case ch == 0x1f&'w':
+			input = input[:ed.prevWordStart(input, len(input))]
+		case ch == ARW_UP:
+			if hist > 0 {
+				hist--
+				input = history[hist]
+			}
+		case ch == ARW_DOWN:
+			if hist < len(history) {
+				hist++
+				input = ""
+				if hist < len(history) {
+					input = history[hist]
+				}
+			}
+		case ch >= 32 && ch < 127:
+			input += string(rune(ch))
+		// Only the first line of pasted text, the rest would be lost
+		// anyway.
+		case ch == PASTE:
+			line := strings.SplitN(ed.keys.pasted, "\n", 2)[0]
+			input += strings.TrimRight(line, "\r")
+		}
+	}
+}
+
+// Read a ':' command and run it. Return false to quit.
+func (ed *Editor) commandMode() bool {
+	line, ok := ed.prompt(":", ed.cmdHistory)
+	line = strings.TrimSpace(line)
+	if !ok || line == "" {
+		return true
+	}
+	ed.cmdHistory = append(ed.cmdHistory, line)
+	// Keep the history small.
+	if len(ed.cmdHistory) > 50 {
+		ed.cmdHistory = ed.cmdHistory[1:]
+	}
+	return ed.execCommand(line)
+}
+
+// Run a command line such as "w", "set tabstop=4" or "goto 50".
+// Return false to quit.
+func (ed *Editor) execCommand(line string) bool {
+	// "!cmd" filters the buffer through a shell command.
+	if strings.HasPrefix(line, "!") {
+		if command := strings.TrimSpace(line[1:]); command != "" {
+			ed.filterBuffer(command)
+		} else {
+			ed.fail("Usage: !command")
+		}
+		return true
+	}
+	fields := strings.Fields(line)
+	name, args := fields[0], fields[1:]
+	// A bare number, or line:col, jumps there, or to that offset in the hex
+	// view.
+	if _, _, ok := parseLineCol(name); ok || (ed.hex != nil && strings.HasPrefix(name, "0x")) {
+		name, args = "goto", fields
+	}
+	switch name {
+	case "q":
+		return ed.quit()
+	// There is one buffer, so the "all buffers" forms act on it.
+	case "qa":
+		if ed.dirty {
+			answer, ok := ed.prompt(fmt.Sprintf("%s has unsaved changes, quit anyway? (y/n) ", ed.displayName()), nil)
+			if !ok || (answer != "y" && answer != "yes") {
+				break
+			}
+		}
+		// Asked already, so not counted against quittimes.
+		return ed.leave()
+	case "wqa":
+		if ed.dirty && ed.filename != "" && !ed.write(nil) {
+			// write already said why.
+			ed.fail("Not quitting, %s: %s", ed.displayName(), ed.statusmsg)
+			break
+		}
+		return ed.quit()
+	case "cq":
+		// Quit with an error status, e.g. to abort a commit.
+		ed.exitCode = 1
+		return ed.leave()
+	case "w":
+		// For git and the like, done once the file is written.
+		if ed.write(args) && ed.cfg.quitOnSave {
+			return ed.quit()
+		}
+	case "wq":
+		if ed.write(args) {
+			return ed.quit()
+		}
+	case "set":
+		if len(args) == 0 {
+			ed.fail("Usage: set name=value")
+			break
+		}
+		if err := ed.cfg.set(strings.Join(args, " ")); err != nil {
+			ed.fail("set: %v", err)
+			break
+		}
+		ed.showConfigWarnings()
+		ed.searchAgain()
+		ed.updateRows()
+	case "ours":
+		ed.resolveConflict(KEEP_OURS)
+	case "theirs":
+		ed.resolveConflict(KEEP_THEIRS)
+	case "both":
+		ed.resolveConflict(KEEP_BOTH)
+	case "fixeol":
+		style := ""
+		if len(args) > 0 {
+			style = args[0]
+		}
+		ed.fixLineEndings(style)
+	case "e":
+		if len(args) != 1 {
+			ed.fail("Usage: e file")
+			break
+		}
+		ed.edit(args[0], 0, 0)
+	case "noreadonly":
+		// Saving replaces the file by renaming over it, which only needs
+		// the directory to be writable, and keeps its permissions.
+		ed.readOnly = false
+		ed.setStatus("Editing read-only file, saving may fail")
+	case "tag":
+		// With a name, look that up instead of the word under the cursor.
+		ed.jumpToTag(strings.Join(args, " "))
+	case "r":
+		if len(args) == 0 {
+			name, ok := ed.prompt("Insert file: ", nil)
+			if name = strings.TrimSpace(name); !ok || name == "" {
+				break
+			}
+			args = []string{name}
+		}
+		ed.readFile(args[0])
+	case "run":
+		// The rest of the line as typed, quoting and all.
+		command := strings.TrimSpace(line[len(name):])
+		if command == "" {
+			ed.fail("Usage: run command")
+			break
+		}
+		ed.runToPane(command)
+	case "grep", "grep!":
+		if len(args) == 0 {
+			ed.grepPrompt()
+			break
+		}
+		// "grep!" searches ignored files too.
+		ed.grepToPane(strings.TrimSpace(line[len(name):]), name == "grep!")
+	case "writeto":
+		ed.writeTo(strings.Join(args, " "))
+	case "trim":
+		ed.trimTrailing()
+	case "hex":
+		ed.toggleHex()
+	case "wc":
+		ed.wordCount()
+	case "goto":
+		if ed.hex != nil && len(args) == 1 {
+			ed.hexGoto(args[0])
+			break
+		}
+		line, col, ok := 0, 0, false
+		if len(args) == 1 {
+			line, col, ok = parseLineCol(args[0])
+		}
+		if !ok {
+			ed.fail("Usage: goto line[:col]")
+			break
+		}
+		ed.jumpTo(line, col)
+	default:
+		// Any action a key can be bound to is also a command.
+		if action, ok := actions[name]; ok {
+			return action(ed)
+		}
+		ed.fail("Unknown command: %s", name)
+	}
+	return true
+}
+
+// Parse "42" or "42:10", a line and optionally a column, both from 1. col
+// is 0 without one.
+func parseLineCol(s string) (line, col int, ok bool) {
+	l, c := s, ""
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		l, c = s[:i], s[i+1:]
+	}
+	line, err := strconv.Atoi(l)
+	if err != nil || line < 1 {
+		return 0, 0, false
+	}
+	if c != "" {
+		if col, err = strconv.Atoi(c); err != nil || col < 1 {
+			return 0, 0, false
+		}
+	}
+	return line, col, true
+}
+
+// Save the buffer to the given path, or the current file when none is given.
+// Report the outcome in the status line and return whether it succeeded.
+func (ed *Editor) write(args []string) bool {
+	filename := ed.filename
+	if len(args) > 0 {
+		filename = args[0]
+	}
+	if filename == "" {
+		ed.fail("No file name")
+		return false
+	}
+	if !ed.checkWritable() {
+		return false
+	}
+	// Saving an unnamed buffer, or under another name, mustn't clobber
+	// some other file unasked.
+	if filename != ed.filename && !ed.sameFile(filename) {
+		if _, err := os.Stat(filename); err == nil {
+			answer, ok := ed.prompt(fmt.Sprintf("%s exists, overwrite? (y/n) ", filename), nil)
+			if !ok || (answer != "y" && answer != "yes") {
+				ed.fail("Not written")
+				return false
+			}
+		}
+	} else if (ed.diskNewer || ed.changedOnDisk()) && !ed.confirmOverwrite() {
+		return false
+	}
+	if ed.cfg.trimOnSave {
+		ed.trimTrailing()
+	}
+	// A formatter failing leaves both the buffer and the file untouched.
+	if err := ed.format(); err != nil {
+		ed.fail("Can't format: %v", err)
+		return false
+	}
+	n, err := ed.save(filename)
+	if err != nil {
+		ed.fail("Can't save: %v", err)
+		return false
+	}
+	if ed.filename == "" {
+		ed.filename = filename
+		ed.branch = gitBranch(filename)
+		ed.selectSyntax()
+	}
+	if filename == ed.filename {
+		ed.dirty, ed.diskNewer = false, false
+		ed.recordDiskState()
+		ed.savePosition()
+		ed.saveFolds()
+		// What formatting on save changed goes along, as its own group.
+		ed.breakUndo()
+		ed.savedUndo = ed.undoState()
+		ed.saveUndo()
+	}
+	ed.setStatus("%d bytes written to disk", n)
+	return true
+}
+
+// Write the buffer to another file, keeping the buffer's own file name and
+// modified state. Asks before overwriting an existing file.
+func (ed *Editor) writeTo(filename string) {
+	if filename == "" {
+		name, ok := ed.prompt("Write copy to: ", nil)
+		if filename = strings.TrimSpace(name); !ok || filename == "" {
+			return
+		}
+	}
+	if ed.sameFile(filename) {
+		// A copy over the file itself is a plain save, or the buffer
+		// would stay modified and the file look changed on disk.
+		ed.write(nil)
+		return
+	}
+	if _, err := os.Stat(filename); err == nil {
+		answer, ok := ed.prompt(fmt.Sprintf("%s exists, overwrite? (y/n) ", filename), nil)
+		if !ok || (answer != "y" && answer != "yes") {
+			ed.fail("Not written")
+			return
+		}
+	}
+	data := ed.Contents()
+	if err := writeFileAtomic(filename, []byte(data), ed.cfg.followSymlinks); err != nil {
+		ed.fail("Can't write %s: %v", filename, err)
+		return
+	}
+	ed.setStatus("%d bytes written to %s", len(data), filename)
+}
+
+// Before the buffer is dropped, ask whether to save its unsaved changes.
+// Return false if the user calls it off, or saving fails.
+func (ed *Editor) confirmLeave() bool {
+	if !ed.dirty {
+		return true
+	}
+	answer, ok := ed.prompt(fmt.Sprintf("%s has unsaved changes, save first? (y/n) ", ed.displayName()), nil)
+	switch {
+	case ok && (answer == "y" || answer == "yes"):
+		// write says why when it fails.
+		return ed.write(nil)
+	case ok && (answer == "n" || answer == "no"):
+		// The changes go with the buffer.
+		return true
+	}
+	ed.fail("No write since last change (:w first)")
+	return false
+}
+
+// Replace the buffer with filename and put the cursor at line, col, or with
+// line 0 where it was last left. A file that doesn't exist yet gives an empty
+// buffer to be saved under its name. The file being left becomes the
+// alternate file. Unsaved changes are saved or dropped first, as the user
+// answers, or the switch is called off.
+func (ed *Editor) edit(filename string, line, col int) bool {
+	// Only a file that isn't there is new, one that can't be read, e.g.
+	// for its permissions, keeps the current buffer.
+	if f, err := os.Open(filename); err == nil {
+		fi, err := f.Stat()
+		f.Close()
+		if err == nil && fi.IsDir() {
+			ed.fail("Can't open %s: is a directory", filename)
+			return false
+		}
+	} else if !os.IsNotExist(err) {
+		ed.fail("Can't open %s: %v", filename, err)
+		return false
+	}
+	open, lazy := ed.confirmSize(filename)
+	if !open || !ed.confirmLeave() {
+		return false
+	}
+	ed.stopFollow()
+	prev, cy, cx := ed.filename, ed.cy, ed.cx
+	ed.savePosition()
+	ed.saveFolds()
+	ed.closeBuffer()
+	if err := ed.open(filename, lazy); err != nil {
+		ed.closeBuffer()
+		ed.filename = filename
+		ed.branch = gitBranch(filename)
+		ed.selectSyntax()
+		if !os.IsNotExist(err) {
+			ed.fail("Can't open %s: %v", filename, err)
+		}
+	}
+	ed.jumpTo(line, col)
+	if line == 0 {
+		ed.restorePosition()
+	}
+	if prev != "" && prev != filename {
+		ed.altFile, ed.altCy, ed.altCx = prev, cy, cx
+	}
+	return true
+}
+
+// Close the file without quitting, leaving an empty buffer with no name.
+// The file closed becomes the alternate file.
+func (ed *Editor) close() {
+	if ed.filename == "" && ed.numRows() == 0 {
+		ed.setStatus("No file to close")
+		return
+	}
+	if !ed.confirmLeave() {
+		return
+	}
+	ed.stopFollow()
+	name, cy, cx := ed.filename, ed.cy, ed.cx
+	ed.savePosition()
+	ed.saveFolds()
+	ed.closeBuffer()
+	if name != "" {
+		ed.altFile, ed.altCy, ed.altCx = name, cy, cx
+		ed.setStatus("Closed %s", ed.cfg.showPath(name))
+	}
+}
+
+// Switch back to the file edited before the current one.
+func (ed *Editor) alternate() {
+	if ed.altFile == "" {
+		ed.setStatus("No alternate file")
+		return
+	}
+	ed.edit(ed.altFile, ed.altCy+1, ed.altCx+1)
+}
+
+// Default of the quittimes setting.
+const QUIT_TIMES = 3
+
+// Quit, with exit status 1 if there are unsaved changes. Those are only
+// given up after quittimes quits in a row, 0 quits at once.
+func (ed *Editor) quit() bool {
+	if ed.dirty {
+		ed.quitPresses++
+		if left := ed.cfg.quitTimes - ed.quitPresses; left > 0 {
+			times := fmt.Sprintf("%d more times", left)
+			if left == 1 {
+				times = "once more"
+			}
+			save := ":w"
+			if ed.filename == "" {
+				save = ":w file"
+			}
+			ed.setStatus("%s has unsaved changes, quit %s to lose them or %s to save", ed.displayName(), times, save)
+			return true
+		}
+	}
+	return ed.leave()
+}
+
+// Stop the editor, with exit status 1 if there are unsaved changes, and
+// clear the screen. Run resets the terminal after. Always false,
+// for processKeyPress to return.
+func (ed *Editor) leave() bool {
+	if ed.dirty {
+		ed.exitCode = 1
+	}
+	// Clear screen on exit.
+	io.WriteString(ed.out, "\x1b[H\x1b[2J")
+	return false
+}
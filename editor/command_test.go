@@ -0,0 +1,188 @@
+package editor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestQuitTimes(t *testing.T) {
+	tests := []struct {
+		name      string
+		quitTimes int
+		file      bool
+		keys      string
+		// Tabs left in the row, one per Tab read before quitting.
+		tabs int
+	}{
+		{"named file asks again", 3, true, "\t\x11\t", 2},
+		{"named file quits at last", 3, true, "\t\x11\x11\x11\t", 1},
+		{"unnamed buffer asks again", 3, false, "\t\x11\t", 2},
+		{"two quits", 2, false, "\t\x11\x11\t", 1},
+		{"other keys start over", 2, true, "\t\x11\t\x11\t", 3},
+		{"zero quits at once", 0, true, "\t\x11\t", 1},
+		{"clean buffer quits at once", 3, true, "\x11\t", 0},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.quitTimes = tt.quitTimes
+		name := ""
+		if tt.file {
+			name = writeTemp(t, "a.txt", "\n")
+		}
+		ed, code := runKeys(t, cfg, name, tt.keys)
+		if tabs := strings.Count(ed.Contents(), "\t"); tabs != tt.tabs {
+			t.Errorf("%s: %d tabs typed before quitting, want %d", tt.name, tabs, tt.tabs)
+		}
+		want := 0
+		if tt.tabs > 0 {
+			want = 1
+		}
+		if code != want {
+			t.Errorf("%s: exit status %d, want %d", tt.name, code, want)
+		}
+	}
+}
+
+func TestQuitMessageNamesTheFile(t *testing.T) {
+	ed, _ := runKeys(t, DefaultConfig(), writeTemp(t, "a.txt", "\n"), "\t\x11")
+	if !strings.Contains(ed.statusmsg, "a.txt has unsaved changes, quit 2 more times") {
+		t.Errorf("status %q", ed.statusmsg)
+	}
+}
+
+func TestQuitAll(t *testing.T) {
+	// Each types a tab to have unsaved changes, then another after the
+	// command, which is only there if the editor kept running.
+	tests := []struct {
+		keys string
+		tabs int
+		code int
+	}{
+		{":qa\ry\r", 1, 1},
+		{":qa\rn\r", 2, 1},
+		{":qa\r\x1b", 2, 1},
+		{":cq\r", 1, 1},
+		{":wqa\r", 1, 0},
+	}
+	for _, tt := range tests {
+		path := writeTemp(t, "a.txt", "\n")
+		ed, code := runKeys(t, DefaultConfig(), path, "\t"+tt.keys+"\t")
+		if tabs := strings.Count(ed.Contents(), "\t"); tabs != tt.tabs || code != tt.code {
+			t.Errorf("%q: %d tabs typed, exit status %d, want %d, %d", tt.keys, tabs, code, tt.tabs, tt.code)
+		}
+	}
+}
+
+func TestEditUnreadableFile(t *testing.T) {
+	path := writeTemp(t, "a.txt", "a\n")
+	// A path through a file fails with something other than not existing,
+	// running as root too.
+	ed, _ := runKeys(t, DefaultConfig(), path, ":e "+path+"/b\r")
+	if ed.Filename() != path || ed.Contents() != "a\n" {
+		t.Errorf("editing %q with contents %q, want %s kept", ed.Filename(), ed.Contents(), path)
+	}
+	if !strings.HasPrefix(ed.statusmsg, "Can't open") {
+		t.Errorf("status %q, want why it can't be opened", ed.statusmsg)
+	}
+	ed, _ = runKeys(t, DefaultConfig(), path, ":e "+filepath.Dir(path)+"\r")
+	if ed.Filename() != path || ed.Contents() != "a\n" {
+		t.Errorf("editing %q with contents %q after opening a directory, want %s kept", ed.Filename(), ed.Contents(), path)
+	}
+	if os.Getuid() != 0 {
+		unreadable := writeTemp(t, "c.txt", "c\n")
+		if err := os.Chmod(unreadable, 0); err != nil {
+			t.Fatal(err)
+		}
+		ed, _ = runKeys(t, DefaultConfig(), path, ":e "+unreadable+"\r")
+		if ed.Filename() != path || ed.Contents() != "a\n" {
+			t.Errorf("editing %q with contents %q after opening an unreadable file, want %s kept", ed.Filename(), ed.Contents(), path)
+		}
+	}
+
+	// One that doesn't exist is a new file.
+	name := path + ".new"
+	ed, _ = runKeys(t, DefaultConfig(), path, ":e "+name+"\r")
+	if ed.Filename() != name || ed.Contents() != "" {
+		t.Errorf("editing %q with contents %q, want an empty %s", ed.Filename(), ed.Contents(), name)
+	}
+}
+
+func TestLeaveDirtyBuffer(t *testing.T) {
+	// Each starts by typing an x into a.txt.
+	tests := []struct {
+		keys string
+		// The file open after, what a.txt then holds, and whether leaving
+		// was called off.
+		open, saved string
+		refused     bool
+	}{
+		{":e b.txt\ry\r", "b.txt", "xa\n", false},
+		{":e b.txt\rn\r", "b.txt", "a\n", false},
+		{":e b.txt\r\x1b", "a.txt", "a\n", true},
+		{":e b.txt\rmaybe\r", "a.txt", "a\n", true},
+		{":close\r\x1b", "a.txt", "a\n", true},
+		// Ctrl-^ back to the alternate file asks about b.txt the same way.
+		{":e b.txt\ry\r" + insertKey + "y\x1b\x1e\x1b", "b.txt", "xa\n", true},
+	}
+	for _, tt := range tests {
+		a := writeTemp(t, "a.txt", "a\n")
+		dir := filepath.Dir(a)
+		if err := ioutil.WriteFile(filepath.Join(dir, "b.txt"), []byte("b\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chdir(dir); err != nil {
+			t.Fatal(err)
+		}
+		ed, _ := runKeys(t, DefaultConfig(), "a.txt", insertKey+"x\x1b"+tt.keys)
+		os.Chdir(wd)
+		if ed.Filename() != tt.open {
+			t.Errorf("%q: editing %s, want %s", tt.keys, ed.Filename(), tt.open)
+		}
+		if data, err := ioutil.ReadFile(a); err != nil || string(data) != tt.saved {
+			t.Errorf("%q: a.txt is %q, %v, want %q", tt.keys, data, err, tt.saved)
+		}
+		if tt.refused && (!ed.Dirty() || ed.statusmsg != "No write since last change (:w first)") {
+			t.Errorf("%q: dirty %v, status %q", tt.keys, ed.Dirty(), ed.statusmsg)
+		}
+	}
+}
+
+func TestGotoLineCol(t *testing.T) {
+	text := strings.Repeat("line\n", 41) + strings.Repeat("x", 200) + "\n" + strings.Repeat("line\n", 8)
+	path := writeTemp(t, "a.txt", text)
+	tests := []struct {
+		command string
+		cy, cx  int
+	}{
+		{"42", 41, 0},
+		{"42:10", 41, 9},
+		{"goto 42:10", 41, 9},
+		{"goto 42", 41, 0},
+		// Clamped to the line and to the buffer.
+		{"42:500", 41, 200},
+		{"1:9", 0, 4},
+		{"99:3", 49, 2},
+	}
+	for _, tt := range tests {
+		ed, _ := runKeys(t, DefaultConfig(), path, ":"+tt.command+"\r")
+		if ed.cy != tt.cy || ed.cx != tt.cx {
+			t.Errorf("%s: cursor at %d,%d, want %d,%d", tt.command, ed.cy, ed.cx, tt.cy, tt.cx)
+		}
+		if ed.cy < ed.rowoff || ed.cy >= ed.rowoff+ed.screenrows || ed.cx < ed.coloff || ed.cx >= ed.coloff+ed.textWidth() {
+			t.Errorf("%s: cursor at %d,%d not on screen at %d,%d", tt.command, ed.cy, ed.cx, ed.rowoff, ed.coloff)
+		}
+	}
+	for _, command := range []string{"goto 42:x", "goto :3", "goto 42:0", "goto"} {
+		ed, _ := runKeys(t, DefaultConfig(), path, ":"+command+"\r")
+		if ed.cy != 0 || ed.statusmsg != "Usage: goto line[:col]" {
+			t.Errorf("%s: cursor on row %d, status %q", command, ed.cy, ed.statusmsg)
+		}
+	}
+}
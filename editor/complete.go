@@ -0,0 +1,86 @@
+package editor
+
+import "strings"
+
+// Most words offered for one completion.
+const COMPLETE_MAX = 100
+
+// Words in the buffer starting with prefix and longer than it, each once,
+// nearest to row cy first. ok is false when cancelled.
+func (ed *Editor) completions(prefix string, cy int) (words []string, ok bool) {
+	seen := make(map[string]bool)
+	n := ed.numRows()
+	for d := 0; d < n && len(words) < COMPLETE_MAX; d++ {
+		if ed.scanCancelled(d) {
+			return nil, false
+		}
+		// The row d above cy, then the one d below.
+		for _, y := range []int{cy - d, cy + d} {
+			if y < 0 || y >= n || (d == 0 && y > cy) {
+				continue
+			}
+			chars := ed.row(y).chars
+			for start := 0; start < len(chars); {
+				end := start
+				for end < len(chars) && !ed.isSeparator(chars[end]) {
+					end++
+				}
+				w := chars[start:end]
+				if len(w) > len(prefix) && strings.HasPrefix(w, prefix) && !seen[w] {
+					seen[w] = true
+					words = append(words, w)
+				}
+				start = end + 1
+			}
+		}
+	}
+	return words, true
+}
+
+// Complete the word before the cursor from the words in the buffer. The
+// choices are listed in the message bar: Ctrl-N, Ctrl-P and the arrow keys
+// walk through them, Enter or Tab takes one and any other key gives up.
+func (ed *Editor) complete() {
+	if !ed.checkWritable() || ed.numRows() == 0 {
+		return
+	}
+	chars := ed.rows[ed.cy].chars
+	start := ed.cx
+	for start > 0 && !ed.isSeparator(chars[start-1]) {
+		start--
+	}
+	prefix := chars[start:ed.cx]
+	if prefix == "" {
+		ed.setStatus("No word before the cursor to complete")
+		return
+	}
+	words, ok := ed.completions(prefix, ed.cy)
+	if !ok {
+		return
+	}
+	if len(words) == 0 {
+		ed.setStatus("No completions for %s", prefix)
+		return
+	}
+	for sel := 0; ; {
+		// From the selected word on, the ones before it are a Ctrl-P away.
+		ed.setStatus("(%d/%d) [%s] %s", sel+1, len(words), words[sel], strings.Join(words[sel+1:], " "))
+		ed.refresh()
+		switch ch := ed.keys.readKey(); ch {
+		case 0x1f & 'n', ARW_DOWN, ARW_RIGHT:
+			sel = (sel + 1) % len(words)
+		case 0x1f & 'p', ARW_UP, ARW_LEFT:
+			sel = (sel + len(words) - 1) % len(words)
+		case '\r', '\t':
+			ed.rowSetChars(ed.cy, chars[:ed.cx]+words[sel][len(prefix):]+chars[ed.cx:])
+			ed.cx += len(words[sel]) - len(prefix)
+			ed.setStatus("")
+			return
+		case RESIZE, FOCUS_IN, FOCUS_OUT:
+			// Redraw at the new size and keep choosing.
+		default:
+			ed.setStatus("")
+			return
+		}
+	}
+}
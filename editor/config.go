@@ -0,0 +1,664 @@
+package editor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Widest tabstop and shiftwidth accepted. Files set them with modelines,
+// and every tab is drawn padded to the tab stop, so opening a file with a
+// huge one could take all the memory there is.
+const MAX_INDENT_WIDTH = 32
+
+// User settings, read from the config file at startup and changed at runtime
+// with ":set".
+type Config struct {
+	keymap Keymap
+	// Width of a tab character when rendered.
+	tabStop int
+	// Indent with spaces instead of tabs.
+	expandTabs bool
+	// Spaces per indent level when indenting with spaces, 0 for tabStop.
+	// Lets a file indented by 4 show its tabs 8 wide.
+	shiftWidth int
+	// Backspace in space indentation deletes a whole indent level.
+	smartTab bool
+	// Typing an opener such as "(" adds its closer after the cursor, see
+	// AUTOCLOSE_PAIRS.
+	autoClose bool
+	// Pasted lines are indented for where they go, see reindentPaste.
+	pasteReindent bool
+	// What Tab does: "cursor" inserts a level of indentation at the
+	// cursor, "line" indents the whole line, "leading" indents the whole
+	// line with the cursor in its leading whitespace and inserts at the
+	// cursor elsewhere.
+	tabIndent string
+	// What Backspace does at the end of a line holding only indentation:
+	// "clear" deletes all of it, "join" also joins the line with the one
+	// above, "off" treats it like any other whitespace.
+	blankBackspace string
+	// On save, write through a symlink to the file it points to rather than
+	// refusing to save.
+	followSymlinks bool
+	// End saved files with a newline even when the last line had none.
+	finalNewline bool
+	// Characters that end a word besides whitespace.
+	separators string
+	// Separators for some filetypes instead of the above.
+	ftSeparators map[string]string
+	// Indentation style of some filetypes, over the built-in ones.
+	ftIndent map[string]*indentStyle
+	// Home toggles between first non-blank and column 0, instead of going
+	// to column 0 only.
+	smartHome bool
+	// Files bigger than this many bytes are opened read-only and read from
+	// disk as they are viewed.
+	largeFile int64
+	// Ask before opening files bigger than this many bytes, 0 never asks.
+	confirmSize int64
+	// Mark words missing from the word list in spellFile, in prose buffers.
+	spell     bool
+	spellFile string
+	// Draw guideChar at each indentation level in leading whitespace.
+	indentGuides bool
+	guideChar    string
+	// Drawn on screen rows past the end of the buffer, "" for none.
+	eobChar string
+	// Shown at the right and left screen edge when a row goes on past it.
+	extendsChar, precedesChar string
+	// Rendered columns, counting from 0, marked with a background in
+	// ascending order. Set as 1 based "80,120".
+	colorColumns []int
+	// Draw the part of rows beyond maxWidth columns in a warning color.
+	markLong bool
+	maxWidth int
+	// Mark trailing whitespace, which makes up all of a whitespace-only
+	// line. markTrailingCursor marks it on the cursor line too, where it
+	// may be being typed.
+	markTrailing       bool
+	markTrailingCursor bool
+	// Strip trailing whitespace from every line when saving.
+	trimOnSave bool
+	// Reload a file changed on disk without asking when the buffer has no
+	// changes of its own.
+	autoReload bool
+	// Save the file when the terminal window loses focus, if it has
+	// unsaved changes.
+	autoSave bool
+	// Quit as soon as ":w" succeeds, for $EDITOR style single file edits.
+	quitOnSave bool
+	// Times quit has to be given in a row to drop an unnamed buffer's
+	// text, which unlike a file's has nowhere to be recovered from. 0 or 1
+	// quits at once.
+	quitTimes int
+	// Words highlighted in comments, e.g. TODO.
+	todoMarkers []string
+	// Mark the rows command output from :run reports on, see
+	// applyDiagnostics.
+	diagnostics bool
+	// Keep the matches of the last search marked, until nohl.
+	hlSearch bool
+	// With no message to show, show keys for what the editor is doing in
+	// the message bar.
+	keyHints bool
+	// Searches and replaceword ignore case, and match only whole words.
+	ignoreCase, wholeWord bool
+	// Lines of the old screen still shown after paging.
+	pageOverlap int
+	// Lines kept visible above and below the cursor when scrolling.
+	scrollOff int
+	// Mark leading whitespace that mixes tabs and spaces against the
+	// indentation style.
+	indentLint bool
+	// Format of the status bar, see expandStatus. "" for the built-in one.
+	statusLine string
+	// Shell command whose output is shown in the status bar, see
+	// updateStatusHook, and how often it is run again. 0 runs it only
+	// when another file is opened or on statusrefresh.
+	statusCmd      string
+	statusInterval time.Duration
+	// How a status message too wide for the screen is shown: "truncate"d,
+	// "wrap"ped over up to msgLines lines, or "scroll"ed through.
+	longMsg  string
+	msgLines int
+	// Draw highlights in color. Off by default on terminals that can't,
+	// and when NO_COLOR is set.
+	color bool
+	// Shade the cursor row and column.
+	crosshair bool
+	// Highlight the partner of the block keyword under the cursor, in
+	// filetypes with block pairs, see Syntax.blockPairs.
+	matchPairs bool
+	// Show a minimap of the buffer this many columns wide at the right
+	// edge.
+	minimap      bool
+	minimapWidth int
+	// Show line numbers in the gutter, aligned "left" or "right", with
+	// numberSep between them and the text.
+	number      bool
+	numberAlign string
+	numberSep   string
+	// Banner on the screen shown when no file is open, lines separated by
+	// a literal "\n". "" shows none.
+	welcome string
+	// Reopen files at the position the cursor was left at.
+	restorePos bool
+	// Remember folds in files between sessions, see saveFolds.
+	saveFolds bool
+	// Keep the undo history of a file when saving it, to undo into the
+	// next time it's opened, see saveUndo.
+	undoFile bool
+	// Groups of changes undo keeps, the oldest are dropped past it. Each
+	// holds the lines it changed, so long sessions on big files take
+	// memory. 0 keeps none.
+	undoLevels int
+	// Copies and cuts also go to the terminal's clipboard with OSC 52.
+	// Off by default, as not every terminal takes it.
+	osc52 bool
+	// Paste asks the terminal for its clipboard with OSC 52 first.
+	osc52Paste bool
+	// Copies and cuts kept for paste and yankpop.
+	yankRing int
+	// Hide the cursor while a frame is drawn. Some terminals blink it when
+	// it is shown again after every key.
+	hideCursor bool
+	// Lines of command output shown in the output pane.
+	paneHeight int
+	// Patterns of files grep leaves out, like lines of a .gitignore.
+	ignore []string
+	// How the file name is shown: "given" as it was opened, "absolute",
+	// "relative" to the working directory or "base" name only.
+	pathDisplay string
+	// Directories searched for files opened with gotofile.
+	path []string
+	// Commands the buffer is piped through before saving, by filetype.
+	formatters map[string]string
+	// Problems with settings that were worked around rather than refused,
+	// e.g. a bad value replaced by the default. Shown once the editor is up,
+	// see showConfigWarnings.
+	warnings []string
+}
+
+// The settings used without a config file.
+func DefaultConfig() *Config {
+	return &Config{
+		keymap:         defaultKeymap(),
+		tabStop:        8,
+		smartHome:      true,
+		smartTab:       true,
+		blankBackspace: "off",
+		tabIndent:      "cursor",
+		numberAlign:    "right",
+		quitTimes:      QUIT_TIMES,
+		minimapWidth:   2,
+		numberSep:      " ",
+		longMsg:        "truncate",
+		msgLines:       3,
+		followSymlinks: true,
+		hideCursor:     true,
+		keyHints:       true,
+		paneHeight:     10,
+		pageOverlap:    2,
+		undoLevels:     3000,
+		yankRing:       10,
+		pathDisplay:    "given",
+		ignore:         []string{"node_modules/"},
+		welcome:        "Welcome to this stupid text editor :)",
+		color:          colorTerminal(),
+		separators:     ",.()+-/*=~%<>[];:{}\"'&|!?#^`@\\$",
+		largeFile:      256 << 20,
+		confirmSize:    128 << 20,
+		spellFile:      "/usr/share/dict/words",
+		guideChar:      "\u2502",
+		eobChar:        "~",
+		statusInterval: 5 * time.Second,
+		extendsChar:    "\u00bb",
+		precedesChar:   "\u00ab",
+		maxWidth:       80,
+		todoMarkers:    []string{"TODO", "FIXME", "XXX", "HACK"},
+		formatters:     make(map[string]string),
+		ftSeparators:   make(map[string]string),
+		ftIndent:       make(map[string]*indentStyle),
+	}
+}
+
+// Whether the terminal is taken to show colors: not with NO_COLOR set, see
+// no-color.org, nor when TERM is dumb or, outside the Windows console which
+// doesn't set it, missing.
+func colorTerminal() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	term := os.Getenv("TERM")
+	return term != "dumb" && (term != "" || runtime.GOOS == "windows")
+}
+
+// Location of the config file, e.g. ~/.config/exa/config on Linux.
+func configPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "exa", "config")
+}
+
+// Load the config file on top of the defaults. A missing file is not an
+// error. The file holds one "name = value" setting per line, and lines
+// starting with '#' are comments.
+func loadConfig(path string) (*Config, error) {
+	cfg := DefaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		warned := len(cfg.warnings)
+		if err := cfg.set(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		for i := warned; i < len(cfg.warnings); i++ {
+			cfg.warnings[i] = fmt.Sprintf("%s:%d: %s", path, n, cfg.warnings[i])
+		}
+	}
+	return cfg, s.Err()
+}
+
+// Apply a single "name = value" setting.
+func (cfg *Config) set(line string) error {
+	idx := strings.IndexByte(line, '=')
+	if idx < 0 {
+		return fmt.Errorf("bad setting %q, want name = value", line)
+	}
+	name := strings.TrimSpace(line[:idx])
+	value := strings.TrimSpace(line[idx+1:])
+	switch name {
+	case "bind":
+		return cfg.keymap.bind(value)
+	case "tabstop":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > MAX_INDENT_WIDTH {
+			return fmt.Errorf("bad tabstop %q, want 1 to %d", value, MAX_INDENT_WIDTH)
+		}
+		cfg.tabStop = n
+		return nil
+	case "shiftwidth":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > MAX_INDENT_WIDTH {
+			return fmt.Errorf("bad shiftwidth %q, want 0 to %d", value, MAX_INDENT_WIDTH)
+		}
+		cfg.shiftWidth = n
+		return nil
+	case "expandtab":
+		return parseBool(value, &cfg.expandTabs)
+	case "smarttab":
+		return parseBool(value, &cfg.smartTab)
+	case "autoclose":
+		return parseBool(value, &cfg.autoClose)
+	case "pastereindent":
+		return parseBool(value, &cfg.pasteReindent)
+	case "tabindent":
+		if value != "cursor" && value != "line" && value != "leading" {
+			return fmt.Errorf("bad tabindent %q, want cursor, line or leading", value)
+		}
+		cfg.tabIndent = value
+		return nil
+	case "blankbackspace":
+		if value != "off" && value != "clear" && value != "join" {
+			return fmt.Errorf("bad blankbackspace %q, want off, clear or join", value)
+		}
+		cfg.blankBackspace = value
+		return nil
+	case "separators":
+		cfg.separators = value
+		return nil
+	case "ftseparators":
+		// "filetype:chars", e.g. "lisp:()'" to keep "-" in words.
+		idx := strings.IndexByte(value, ':')
+		if idx <= 0 {
+			return fmt.Errorf("bad ftseparators %q, want filetype:chars", value)
+		}
+		cfg.ftSeparators[strings.TrimSpace(value[:idx])] = value[idx+1:]
+		return nil
+	case "ftindent":
+		// "filetype:style", e.g. "c:spaces:4" or "make:tabs".
+		idx := strings.IndexByte(value, ':')
+		if idx <= 0 {
+			return fmt.Errorf("bad ftindent %q, want filetype:style", value)
+		}
+		style, err := parseIndentStyle(strings.TrimSpace(value[idx+1:]))
+		if err != nil {
+			return err
+		}
+		cfg.ftIndent[strings.TrimSpace(value[:idx])] = style
+		return nil
+	case "followsymlinks":
+		return parseBool(value, &cfg.followSymlinks)
+	case "finalnewline":
+		return parseBool(value, &cfg.finalNewline)
+	case "smarthome":
+		return parseBool(value, &cfg.smartHome)
+	case "indentguides":
+		return parseBool(value, &cfg.indentGuides)
+	case "guidechar", "extends", "precedes":
+		// Must take a single column like the character it replaces.
+		if utf8.RuneCountInString(value) != 1 || !unicode.IsGraphic([]rune(value)[0]) {
+			return fmt.Errorf("bad %s %q, want a single character", name, value)
+		}
+		switch name {
+		case "guidechar":
+			cfg.guideChar = value
+		case "extends":
+			cfg.extendsChar = value
+		case "precedes":
+			cfg.precedesChar = value
+		}
+		return nil
+	case "eobchar":
+		// Empty for blank rows, otherwise like guidechar.
+		if value != "" && (utf8.RuneCountInString(value) != 1 || !unicode.IsGraphic([]rune(value)[0])) {
+			return fmt.Errorf("bad eobchar %q, want a single character or none", value)
+		}
+		cfg.eobChar = value
+		return nil
+	case "colorcolumn":
+		var cols []int
+		for _, f := range strings.Split(value, ",") {
+			if f = strings.TrimSpace(f); f == "" {
+				continue
+			}
+			n, err := strconv.Atoi(f)
+			if err != nil || n < 1 {
+				return fmt.Errorf("bad colorcolumn %q", f)
+			}
+			cols = append(cols, n-1)
+		}
+		sort.Ints(cols)
+		cfg.colorColumns = cols
+		return nil
+	case "marklong":
+		return parseBool(value, &cfg.markLong)
+	case "maxwidth":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("bad maxwidth %q", value)
+		}
+		cfg.maxWidth = n
+		return nil
+	case "marktrailing":
+		return parseBool(value, &cfg.markTrailing)
+	case "marktrailingcursor":
+		return parseBool(value, &cfg.markTrailingCursor)
+	case "trimonsave":
+		return parseBool(value, &cfg.trimOnSave)
+	case "autoreload":
+		return parseBool(value, &cfg.autoReload)
+	case "autosave":
+		return parseBool(value, &cfg.autoSave)
+	case "quitonsave":
+		return parseBool(value, &cfg.quitOnSave)
+	case "quittimes":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			// Better guarded as usual than not at all.
+			cfg.quitTimes = QUIT_TIMES
+			cfg.warnings = append(cfg.warnings, fmt.Sprintf("bad quittimes %q, using %d", value, QUIT_TIMES))
+			return nil
+		}
+		cfg.quitTimes = n
+		return nil
+	case "indentlint":
+		return parseBool(value, &cfg.indentLint)
+	case "statusline":
+		cfg.statusLine = value
+		return nil
+	case "statuscmd":
+		cfg.statusCmd = value
+		return nil
+	case "statusinterval":
+		// In seconds.
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("bad statusinterval %q", value)
+		}
+		cfg.statusInterval = time.Duration(n) * time.Second
+		return nil
+	case "longmsg":
+		if value != "truncate" && value != "wrap" && value != "scroll" {
+			return fmt.Errorf("bad longmsg %q, want truncate, wrap or scroll", value)
+		}
+		cfg.longMsg = value
+		return nil
+	case "msglines":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("bad msglines %q", value)
+		}
+		cfg.msgLines = n
+		return nil
+	case "color":
+		if value == "auto" {
+			cfg.color = colorTerminal()
+			return nil
+		}
+		return parseBool(value, &cfg.color)
+	case "crosshair":
+		return parseBool(value, &cfg.crosshair)
+	case "matchpairs":
+		return parseBool(value, &cfg.matchPairs)
+	case "minimap":
+		return parseBool(value, &cfg.minimap)
+	case "minimapwidth":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("bad minimapwidth %q", value)
+		}
+		cfg.minimapWidth = n
+		return nil
+	case "number":
+		return parseBool(value, &cfg.number)
+	case "numberalign":
+		if value != "left" && value != "right" {
+			return fmt.Errorf("bad numberalign %q, want left or right", value)
+		}
+		cfg.numberAlign = value
+		return nil
+	case "numbersep":
+		// Spaces are trimmed off values, so they go by name.
+		switch {
+		case value == "space":
+			value = " "
+		case value == "bar":
+			value = "|"
+		case value == "none":
+			value = ""
+		case len(value) != 1 || value[0] <= ' ' || value[0] >= 0x7f:
+			return fmt.Errorf("bad numbersep %q, want space, bar, none or one character", value)
+		}
+		cfg.numberSep = value
+		return nil
+	case "welcome":
+		cfg.welcome = value
+		return nil
+	case "restorepos":
+		return parseBool(value, &cfg.restorePos)
+	case "savefolds":
+		return parseBool(value, &cfg.saveFolds)
+	case "undofile":
+		return parseBool(value, &cfg.undoFile)
+	case "hidecursor":
+		return parseBool(value, &cfg.hideCursor)
+	case "ignore":
+		cfg.ignore = nil
+		for _, p := range strings.Split(value, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				cfg.ignore = append(cfg.ignore, p)
+			}
+		}
+		return nil
+	case "pathdisplay":
+		if !validPathDisplay(value) {
+			return fmt.Errorf("bad pathdisplay %q, want %s", value, strings.Join(pathDisplays, ", "))
+		}
+		cfg.pathDisplay = value
+		return nil
+	case "diagnostics":
+		return parseBool(value, &cfg.diagnostics)
+	case "hlsearch":
+		return parseBool(value, &cfg.hlSearch)
+	case "keyhints":
+		return parseBool(value, &cfg.keyHints)
+	case "ignorecase":
+		return parseBool(value, &cfg.ignoreCase)
+	case "wholeword":
+		return parseBool(value, &cfg.wholeWord)
+	case "osc52":
+		return parseBool(value, &cfg.osc52)
+	case "osc52paste":
+		return parseBool(value, &cfg.osc52Paste)
+	case "yankring":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("bad yankring %q", value)
+		}
+		cfg.yankRing = n
+		return nil
+	case "undolevels":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("bad undolevels %q", value)
+		}
+		cfg.undoLevels = n
+		return nil
+	case "pageoverlap":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("bad pageoverlap %q", value)
+		}
+		cfg.pageOverlap = n
+		return nil
+	case "paneheight":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("bad paneheight %q", value)
+		}
+		cfg.paneHeight = n
+		return nil
+	case "todo":
+		cfg.todoMarkers = nil
+		for _, w := range strings.Split(value, ",") {
+			if w = strings.TrimSpace(w); w != "" {
+				cfg.todoMarkers = append(cfg.todoMarkers, w)
+			}
+		}
+		return nil
+	case "scrolloff":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("bad scrolloff %q", value)
+		}
+		cfg.scrollOff = n
+		return nil
+	case "largefile":
+		n, err := parseSize(value)
+		if err != nil {
+			return err
+		}
+		cfg.largeFile = n
+		return nil
+	case "confirmsize":
+		n, err := parseSize(value)
+		if err != nil {
+			return err
+		}
+		cfg.confirmSize = n
+		return nil
+	case "spell":
+		return parseBool(value, &cfg.spell)
+	case "spellfile":
+		cfg.spellFile = value
+		return nil
+	case "path":
+		cfg.path = nil
+		for _, dir := range strings.Split(value, ",") {
+			if dir = strings.TrimSpace(dir); dir != "" {
+				cfg.path = append(cfg.path, dir)
+			}
+		}
+		return nil
+	case "format":
+		// "filetype:command", e.g. "go:gofmt". No command turns it off.
+		idx := strings.IndexByte(value, ':')
+		if idx <= 0 {
+			return fmt.Errorf("bad format %q, want filetype:command", value)
+		}
+		cfg.formatters[strings.TrimSpace(value[:idx])] = strings.TrimSpace(value[idx+1:])
+		return nil
+	}
+	return fmt.Errorf("unknown setting %q", name)
+}
+
+// Parse an on/off value into b.
+func parseBool(value string, b *bool) error {
+	switch strings.ToLower(value) {
+	case "on", "true", "yes", "1":
+		*b = true
+	case "off", "false", "no", "0":
+		*b = false
+	default:
+		return fmt.Errorf("bad boolean %q, want on or off", value)
+	}
+	return nil
+}
+
+// Parse a byte count with an optional K, M or G suffix, e.g. "512M".
+func parseSize(value string) (int64, error) {
+	mult := int64(1)
+	num := strings.ToUpper(value)
+	switch {
+	case strings.HasSuffix(num, "K"):
+		mult = 1 << 10
+	case strings.HasSuffix(num, "M"):
+		mult = 1 << 20
+	case strings.HasSuffix(num, "G"):
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		num = num[:len(num)-1]
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("bad size %q", value)
+	}
+	return n * mult, nil
+}
+
+// Show the settings problems worked around since last time, in place of
+// the status message.
+func (ed *Editor) showConfigWarnings() {
+	if len(ed.cfg.warnings) > 0 {
+		ed.setStatus("%s", strings.Join(ed.cfg.warnings, "; "))
+		ed.cfg.warnings = nil
+	}
+}
@@ -0,0 +1,25 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBadQuitTimesFallsBack(t *testing.T) {
+	path := writeTemp(t, "config", "quittimes = lots\n")
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if cfg.quitTimes != QUIT_TIMES {
+		t.Errorf("quittimes %d, want the default %d", cfg.quitTimes, QUIT_TIMES)
+	}
+	want := path + `:1: bad quittimes "lots", using 3`
+	if len(cfg.warnings) != 1 || cfg.warnings[0] != want {
+		t.Errorf("warnings %q, want %q", cfg.warnings, want)
+	}
+	ed := New(cfg, strings.NewReader(""), nil)
+	if ed.statusmsg != want || len(cfg.warnings) != 0 {
+		t.Errorf("status %q with warnings %q left, want the warning shown once", ed.statusmsg, cfg.warnings)
+	}
+}
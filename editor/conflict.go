@@ -0,0 +1,78 @@
+package editor
+
+// Sides of a merge conflict to keep when resolving it.
+const (
+	KEEP_OURS = iota
+	KEEP_THEIRS
+	KEEP_BOTH
+)
+
+// Locate the merge conflict around row cy. Return the rows of its markers:
+// <<<<<<<, ||||||| (same as mid when there is no base section), ======= and
+// >>>>>>>.
+func (ed *Editor) conflictAt(cy int) (start, base, mid, end int, ok bool) {
+	start = -1
+	for i := cy; i >= 0; i-- {
+		line := ed.rows[i].chars
+		if isConflictMarker(line, '<') {
+			start = i
+			break
+		}
+		// The end marker of an earlier conflict, cy is not inside one.
+		if isConflictMarker(line, '>') && i != cy {
+			return 0, 0, 0, 0, false
+		}
+	}
+	if start < 0 {
+		return 0, 0, 0, 0, false
+	}
+	base, mid = -1, -1
+	for i := start + 1; i < len(ed.rows); i++ {
+		line := ed.rows[i].chars
+		switch {
+		case isConflictMarker(line, '|') && mid < 0:
+			base = i
+		case isConflictMarker(line, '=') && mid < 0:
+			mid = i
+		case isConflictMarker(line, '>') && mid >= 0:
+			if base < 0 {
+				base = mid
+			}
+			return start, base, mid, i, i >= cy
+		case isConflictMarker(line, '<'):
+			return 0, 0, 0, 0, false
+		}
+	}
+	return 0, 0, 0, 0, false
+}
+
+// Replace the conflict under the cursor with the chosen side(s).
+func (ed *Editor) resolveConflict(keep int) {
+	if !ed.checkWritable() {
+		return
+	}
+	start, base, mid, end, ok := ed.conflictAt(ed.cy)
+	if !ok {
+		ed.fail("Not inside a merge conflict")
+		return
+	}
+	var lines []string
+	if keep == KEEP_OURS || keep == KEEP_BOTH {
+		for i := start + 1; i < base; i++ {
+			lines = append(lines, ed.rows[i].chars)
+		}
+	}
+	if keep == KEEP_THEIRS || keep == KEEP_BOTH {
+		for i := mid + 1; i < end; i++ {
+			lines = append(lines, ed.rows[i].chars)
+		}
+	}
+	for i := end; i >= start; i-- {
+		ed.delRow(i)
+	}
+	for i, line := range lines {
+		ed.insertRow(start+i, line)
+	}
+	ed.cy, ed.cx = start, 0
+	ed.clampCursor()
+}
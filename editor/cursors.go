@@ -0,0 +1,151 @@
+package editor
+
+import (
+	"sort"
+	"strings"
+)
+
+// Where a cursor besides the main one is, see addCursor.
+type cursorPos struct {
+	y, x int
+}
+
+// Actions done at every cursor when there are several. Any other key but
+// addcursor and insert goes back to the main cursor alone first.
+var multiCursor = map[string]bool{
+	"left":        true,
+	"right":       true,
+	"up":          true,
+	"down":        true,
+	"home":        true,
+	"end":         true,
+	"wordleft":    true,
+	"wordright":   true,
+	"backspace":   true,
+	"delete":      true,
+	"delwordback": true,
+	"tab":         true,
+	"newline":     true,
+}
+
+// Add a cursor on the next occurrence of the word under the cursor, after
+// the last cursor added and wrapping around the buffer. Only whole words
+// count. The new cursor is as far into its word as the main one.
+func (ed *Editor) addCursor() {
+	if ed.numRows() == 0 {
+		return
+	}
+	chars := ed.row(ed.cy).chars
+	word := ed.wordAt(chars, ed.cx)
+	if word == "" {
+		ed.setStatus("No word under the cursor")
+		return
+	}
+	start := ed.cx
+	for start > 0 && !ed.isSeparator(chars[start-1]) {
+		start--
+	}
+	offset := ed.cx - start
+	from := cursorPos{ed.cy, start}
+	if n := len(ed.cursors); n > 0 {
+		from = cursorPos{ed.cursors[n-1].y, ed.cursors[n-1].x - offset}
+	}
+	n := ed.numRows()
+	for i := 0; i <= n; i++ {
+		y, x := (from.y+i)%n, 0
+		if i == 0 {
+			x = from.x + 1
+		}
+		chars := ed.row(y).chars
+		for x < len(chars) {
+			j := strings.Index(chars[x:], word)
+			if j < 0 {
+				break
+			}
+			s, e := x+j, x+j+len(word)
+			x = s + 1
+			if (s > 0 && !ed.isSeparator(chars[s-1])) || (e < len(chars) && !ed.isSeparator(chars[e])) {
+				continue
+			}
+			if y == ed.cy && s == start {
+				// Back at the main cursor, every one has a cursor.
+				ed.setStatus("No more of %s", word)
+				return
+			}
+			ed.cursors = append(ed.cursors, cursorPos{y, s + offset})
+			ed.setStatus("%d cursors", len(ed.cursors)+1)
+			return
+		}
+	}
+	ed.setStatus("No more of %s", word)
+}
+
+// Run fn at each cursor, the last in the buffer first so the changes it
+// makes don't move the ones still to go. When fn is a change the cursors
+// after it are moved with the text, e.g. along the line as characters are
+// typed before them, or down a line after Enter. Cursors ending up in the
+// same place become one. Return false, e.g. to quit, if fn did at any.
+func (ed *Editor) atEachCursor(change bool, fn func() bool) bool {
+	if len(ed.cursors) == 0 {
+		return fn()
+	}
+	pos := append([]cursorPos{{ed.cy, ed.cx}}, ed.cursors...)
+	order := make([]int, len(pos))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		p, q := pos[order[a]], pos[order[b]]
+		return p.y > q.y || (p.y == q.y && p.x > q.x)
+	})
+	ok := true
+	for k, i := range order {
+		c, rows := pos[i], ed.numRows()
+		ed.cy, ed.cx = c.y, c.x
+		ed.clampCursor()
+		width := 0
+		if ed.numRows() > 0 {
+			width = len(ed.row(ed.cy).chars)
+		}
+		if !fn() {
+			ok = false
+		}
+		pos[i] = cursorPos{ed.cy, ed.cx}
+		if !change {
+			continue
+		}
+		delta := ed.numRows() - rows
+		for _, j := range order[:k] {
+			p := &pos[j]
+			switch {
+			case p.y == c.y && delta == 0 && ed.cy == c.y:
+				// Changed within the line, the rest of it moved by
+				// what was added or taken out.
+				p.x += len(ed.row(c.y).chars) - width
+			case p.y == c.y:
+				// The rest of the line went where the cursor did.
+				p.y, p.x = ed.cy, p.x-c.x+ed.cx
+			case delta < 0 && p.y == c.y+1 && ed.cy == c.y:
+				// The next line was joined onto this one.
+				p.y, p.x = c.y, p.x+ed.cx
+			default:
+				p.y += delta
+			}
+		}
+	}
+	ed.cy, ed.cx = pos[0].y, pos[0].x
+	ed.clampCursor()
+	seen := map[cursorPos]bool{{ed.cy, ed.cx}: true}
+	ed.cursors = ed.cursors[:0]
+	for _, p := range pos[1:] {
+		p.y = clamp(p.y, 0, ed.numRows()-1)
+		if p.y >= 0 {
+			p.x = clamp(p.x, 0, len(ed.row(p.y).chars))
+		}
+		if !seen[p] {
+			seen[p] = true
+			ed.cursors = append(ed.cursors, p)
+		}
+	}
+	return ok
+}
@@ -0,0 +1,50 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+// Ctrl-D adds a cursor.
+const addCursorKey = "\x04"
+
+func TestMultipleCursors(t *testing.T) {
+	tests := []struct {
+		name, text, keys, want string
+	}{
+		{"types at every cursor", "foo x foo\nfoo\n", addCursorKey + addCursorKey + insertKey + "_", "_foo x _foo\n_foo\n"},
+		{"moves every cursor", "foo x foo\nfoo\n", addCursorKey + addCursorKey + "\x1b[F" + insertKey + ";", "foo x foo;\nfoo;\n"},
+		{"only whole words", "foo food foo\n", addCursorKey + insertKey + "X", "Xfoo food Xfoo\n"},
+		{"as far into the word", "xab xab\n", "\x1b[C\x1b[C" + addCursorKey + insertKey + "-", "xa-b xa-b\n"},
+		{"backspace at every cursor", "a1 a1 a1\n", "\x1b[C" + addCursorKey + addCursorKey + "\x1b[C\x7f", "a a a\n"},
+		{"newline at every cursor", "ab ab\n", "\x1b[C" + addCursorKey + "\r", "a\nb a\nb\n"},
+		{"joined at the start of lines", "ab\nab\nab\n", addCursorKey + addCursorKey + "\x1b[B\x7f", "ababab\n"},
+		{"joined at the end of lines", "ab\nab\nab\n", addCursorKey + addCursorKey + "\x1b[F" + deleteKey + insertKey + "!", "ab!ab!ab!\n"},
+		{"wraps around", "foo\nfoo\n", "\x1b[B" + addCursorKey + insertKey + "!", "!foo\n!foo\n"},
+		{"stops at the main cursor", "foo foo\n", addCursorKey + addCursorKey + addCursorKey + insertKey + "!", "!foo !foo\n"},
+		{"Escape goes back to one cursor", "foo foo\n", addCursorKey + "\x1b" + insertKey + "!", "!foo foo\n"},
+		{"other keys go back to one cursor", "foo foo\n", addCursorKey + "\x0c" + insertKey + "!", "!foo foo\n"},
+	}
+	for _, tt := range tests {
+		ed, _ := runKeys(t, DefaultConfig(), writeTemp(t, "a.txt", tt.text), tt.keys)
+		if got := ed.Contents(); got != tt.want {
+			t.Errorf("%s: buffer %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSecondaryCursorsDrawn(t *testing.T) {
+	ed, out := frameEditor(t, 20, 4, "a.txt", "ab ab\nab\n")
+	ed.cursors = []cursorPos{{0, 3}, {1, 2}}
+	ed.refresh()
+	frame := out.String()
+	if !strings.Contains(frame, "\x1b[1;1Hab \x1b[m\x1b[7ma\x1b[mb") {
+		t.Errorf("frame %q, want the cursor on the second word inverted", frame)
+	}
+	if !strings.Contains(frame, "\x1b[2;1Hab\x1b[7m \x1b[m") {
+		t.Errorf("frame %q, want the cursor at the end of the line as an inverted space", frame)
+	}
+	if strings.Contains(frame, "\x1b[1;1H\x1b[7m") {
+		t.Errorf("frame %q, the main cursor is the terminal's", frame)
+	}
+}
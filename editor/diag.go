@@ -0,0 +1,67 @@
+package editor
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Columns of the gutter holding diagnostic signs, the sign and a space.
+const DIAG_GUTTER = 2
+
+// Mark the rows of the buffer that lines of command output report on, such
+// as "main.go:12:5: undefined: x", with the message. A row keeps it until
+// it's edited. Return the number of rows marked.
+func (ed *Editor) applyDiagnostics(lines []string) int {
+	if !ed.cfg.diagnostics || ed.lazy != nil || ed.filename == "" {
+		return 0
+	}
+	// Most lines name the same few files, stat each once.
+	same := make(map[string]bool)
+	n := 0
+	for _, line := range lines {
+		m := locationPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		isSame, ok := same[m[1]]
+		if !ok {
+			isSame = ed.sameFile(m[1])
+			same[m[1]] = isSame
+		}
+		y, _ := strconv.Atoi(m[2])
+		if !isSame || y < 1 || y > len(ed.rows) || ed.rows[y-1].diag != "" {
+			continue
+		}
+		msg := strings.TrimSpace(strings.TrimPrefix(line[len(m[0]):], ":"))
+		if msg == "" {
+			msg = line
+		}
+		ed.rows[y-1].diag = msg
+		ed.hasDiags = true
+		n++
+	}
+	return n
+}
+
+// Move the cursor to the next (dir 1) or previous (dir -1) row with a
+// diagnostic, wrapping around the buffer, and show its message.
+func (ed *Editor) jumpDiagnostic(dir int) {
+	n := len(ed.rows)
+	for i := 1; i <= n && ed.hasDiags; i++ {
+		y := ((ed.cy+dir*i)%n + n) % n
+		if msg := ed.rows[y].diag; msg != "" {
+			ed.cy, ed.cx = y, 0
+			ed.setStatus("%s", msg)
+			return
+		}
+	}
+	ed.setStatus("No diagnostics")
+}
+
+// Drop all diagnostics, and the gutter with them.
+func (ed *Editor) clearDiagnostics() {
+	for i := range ed.rows {
+		ed.rows[i].diag = ""
+	}
+	ed.hasDiags = false
+}
@@ -0,0 +1,245 @@
+package editor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Lines of unchanged text shown around each change.
+const DIFF_CONTEXT = 3
+
+// Most lines added and removed that the diff looks for the shortest way to
+// make. Past that, the rest of the changed span shows as removed and added
+// whole rather than taking forever.
+const DIFF_MAX_EDITS = 2000
+
+// Show what saving would change in the file, as a unified diff in the
+// output pane. The buffer is left alone. Return false when saving would
+// change nothing, true otherwise, also when there is no telling.
+func (ed *Editor) diffWithDisk() bool {
+	if ed.filename == "" {
+		ed.fail("No file name")
+		return true
+	}
+	if ed.lazy != nil {
+		// Viewed straight from disk, there can't be changes.
+		ed.setStatus("No changes")
+		return false
+	}
+	disk, err := ioutil.ReadFile(ed.filename)
+	if err != nil && !os.IsNotExist(err) {
+		ed.fail("Can't read %s: %v", ed.displayName(), err)
+		return true
+	}
+	a, b := splitLines(string(disk)), splitLines(ed.Contents())
+	ops, err := diffLines(a, b, ed.keys.cancelled)
+	if err == errCancelled {
+		ed.setStatus("Cancelled")
+		return true
+	}
+	lines := unifiedDiff(a, b, ops)
+	if len(lines) == 0 {
+		ed.setStatus("No changes")
+		return false
+	}
+	ed.showPane("Changes to "+ed.displayName()+" if saved", lines)
+	ed.pane.diff = true
+	return true
+}
+
+// Split text into lines, each with its line ending.
+func splitLines(text string) []string {
+	var lines []string
+	for text != "" {
+		i := strings.IndexByte(text, '\n') + 1
+		if i == 0 {
+			i = len(text)
+		}
+		lines, text = append(lines, text[:i]), text[i:]
+	}
+	return lines
+}
+
+// The shortest list of steps turning a into b: ' ' keeps a line of a, '-'
+// drops one and '+' adds a line of b.
+func diffLines(a, b []string, cancelled func() bool) ([]byte, error) {
+	// Lines the same at both ends are cheap to take off first, and often
+	// all that there is to set apart.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	mid, err := shortestEdit(a[pre:len(a)-suf], b[pre:len(b)-suf], cancelled)
+	if err != nil {
+		return nil, err
+	}
+	ops := make([]byte, 0, pre+len(mid)+suf)
+	ops = append(ops, strings.Repeat(" ", pre)...)
+	ops = append(ops, mid...)
+	return append(ops, strings.Repeat(" ", suf)...), nil
+}
+
+// Myers' algorithm: for each number of edits d the furthest point on each
+// diagonal k = x-y reached with d edits, kept to walk back the way taken.
+func shortestEdit(a, b []string, cancelled func() bool) ([]byte, error) {
+	n, m := len(a), len(b)
+	off := n + m + 1
+	v := make([]int, 2*off+1)
+	// The points reached with d edits, diagonals -d to d.
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		if d > DIFF_MAX_EDITS {
+			return replaceAll(n, m), nil
+		}
+		if d%64 == 63 && cancelled() {
+			return nil, errCancelled
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				// Down from diagonal k+1, adding a line of b.
+				x = v[off+k+1]
+			} else {
+				// Right from diagonal k-1, dropping a line of a.
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+				return editPath(trace, n, m), nil
+			}
+		}
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+	}
+	return replaceAll(n, m), nil
+}
+
+// Walk back from the end through the points of shortestEdit, collecting
+// the steps in reverse.
+func editPath(trace [][]int, n, m int) []byte {
+	var ops []byte
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		pk := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			pk = k + 1
+		}
+		px := at(pk)
+		py := px - pk
+		for x > px && y > py {
+			ops = append(ops, ' ')
+			x, y = x-1, y-1
+		}
+		if pk == k+1 {
+			ops = append(ops, '+')
+		} else {
+			ops = append(ops, '-')
+		}
+		x, y = px, py
+	}
+	for ; x > 0; x-- {
+		ops = append(ops, ' ')
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// Steps dropping all n lines and adding all m.
+func replaceAll(n, m int) []byte {
+	return []byte(strings.Repeat("-", n) + strings.Repeat("+", m))
+}
+
+// Format the steps from a to b as the hunks of a unified diff, without the
+// file names. Empty if nothing changed.
+func unifiedDiff(a, b []string, ops []byte) []string {
+	// Line of a and of b at each step.
+	ia, ib := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for p, op := range ops {
+		ia[p+1], ib[p+1] = ia[p], ib[p]
+		if op != '+' {
+			ia[p+1]++
+		}
+		if op != '-' {
+			ib[p+1]++
+		}
+	}
+	var lines []string
+	for p := 0; p < len(ops); {
+		if ops[p] == ' ' {
+			p++
+			continue
+		}
+		start := clamp(p-DIFF_CONTEXT, 0, p)
+		// Changes closer than twice the context share a hunk.
+		end, last := p, p
+		for end < len(ops) && end-last <= 2*DIFF_CONTEXT {
+			if ops[end] != ' ' {
+				last = end
+			}
+			end++
+		}
+		end = clamp(last+DIFF_CONTEXT+1, 0, len(ops))
+		lines = append(lines, fmt.Sprintf("@@ -%s +%s @@",
+			hunkRange(ia[start], ia[end]), hunkRange(ib[start], ib[end])))
+		for q := start; q < end; q++ {
+			// Only the side the step is about has a line there.
+			var line string
+			if ops[q] == '+' {
+				line = b[ib[q]]
+			} else {
+				line = a[ia[q]]
+			}
+			text := strings.TrimSuffix(line, "\n")
+			if strings.HasSuffix(text, "\r") {
+				// Otherwise a line ending changed is no change to see.
+				text = strings.TrimSuffix(text, "\r") + "^M"
+			}
+			lines = append(lines, string(ops[q])+text)
+			if !strings.HasSuffix(line, "\n") {
+				lines = append(lines, `\ No newline at end of file`)
+			}
+		}
+		p = end
+	}
+	return lines
+}
+
+// Class to draw a line of unifiedDiff output with.
+func diffHighlight(line string) uint8 {
+	switch {
+	case strings.HasPrefix(line, "@@"):
+		return HL_DIFF_HUNK
+	case strings.HasPrefix(line, "+"):
+		return HL_DIFF_ADD
+	case strings.HasPrefix(line, "-"):
+		return HL_DIFF_DEL
+	}
+	return HL_NORMAL
+}
+
+// Lines from through to of a file, 0-based and exclusive, as a hunk header
+// gives them: 1-based, and for no lines the one before.
+func hunkRange(from, to int) string {
+	if to-from == 1 {
+		return fmt.Sprint(from + 1)
+	}
+	if to == from {
+		return fmt.Sprintf("%d,0", from)
+	}
+	return fmt.Sprintf("%d,%d", from+1, to-from)
+}
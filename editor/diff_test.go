@@ -0,0 +1,60 @@
+package editor
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	never := func() bool { return false }
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{"same", "x\ny\n", "x\ny\n", nil},
+		{"added at end", "x\n", "x\ny\n", []string{"@@ -1 +1,2 @@", " x", "+y"}},
+		{"removed at end", "x\ny\n", "x\n", []string{"@@ -1,2 +1 @@", " x", "-y"}},
+		{"added at start", "y\n", "x\ny\n", []string{"@@ -1 +1,2 @@", "+x", " y"}},
+		{"removed at start", "x\ny\n", "y\n", []string{"@@ -1,2 +1 @@", "-x", " y"}},
+		{"empty old", "", "x\ny\n", []string{"@@ -0,0 +1,2 @@", "+x", "+y"}},
+		{"empty new", "x\ny\n", "", []string{"@@ -1,2 +0,0 @@", "-x", "-y"}},
+		{"changed", "a\nb\nc\n", "a\nB\nc\n", []string{"@@ -1,3 +1,3 @@", " a", "-b", "+B", " c"}},
+		{"newline added", "x", "x\n", []string{"@@ -1 +1 @@", "-x", `\ No newline at end of file`, "+x"}},
+		{"line ending", "x\r\n", "x\n", []string{"@@ -1 +1 @@", "-x^M", "+x"}},
+	}
+	for _, tt := range tests {
+		a, b := splitLines(tt.a), splitLines(tt.b)
+		ops, err := diffLines(a, b, never)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := unifiedDiff(a, b, ops); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestUnifiedDiffHunks(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 20; i++ {
+		line := fmt.Sprintf("line %d\n", i)
+		a, b = append(a, line), append(b, line)
+	}
+	// Changes far apart get hunks of their own, with DIFF_CONTEXT lines
+	// around each.
+	b[1], b[18] = "one\n", "two\n"
+	ops, _ := diffLines(a, b, func() bool { return false })
+	var headers []string
+	for _, line := range unifiedDiff(a, b, ops) {
+		if strings.HasPrefix(line, "@@") {
+			headers = append(headers, line)
+		}
+	}
+	want := []string{"@@ -1,5 +1,5 @@", "@@ -16,5 +16,5 @@"}
+	if !reflect.DeepEqual(headers, want) {
+		t.Errorf("hunks %q, want %q", headers, want)
+	}
+}
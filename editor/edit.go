@@ -0,0 +1,304 @@
+package editor
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Append s to the end of row at.
+func (ed *Editor) rowAppend(at int, s string) {
+	ed.recordRows(at, 1, 1)
+	row := &ed.rows[at]
+	row.chars += s
+	row.update(ed.cfg.tabStop)
+	ed.updateSyntax(at)
+	ed.dirty = true
+}
+
+// Delete the character before the cursor, or join the line with the one
+// above at the start of the line. With smarttab, in space indentation it
+// deletes back to the previous tab stop, a whole indent level. At the end
+// of a line holding only indentation, blankbackspace can have it clear the
+// line or remove it altogether. Between an empty pair autoclose deletes
+// both sides.
+func (ed *Editor) backspace() {
+	if !ed.checkWritable() || ed.numRows() == 0 || ed.deletePair() {
+		return
+	}
+	row := &ed.rows[ed.cy]
+	if ed.cx > 0 && ed.cx == len(row.chars) && row.blank() {
+		switch ed.cfg.blankBackspace {
+		case "clear":
+			ed.rowDelChars(ed.cy, 0, ed.cx)
+			ed.cx = 0
+			return
+		case "join":
+			ed.rowDelChars(ed.cy, 0, ed.cx)
+			ed.cx = 0
+		}
+	}
+	if ed.cx == 0 {
+		if ed.cy == 0 {
+			return
+		}
+		prev := ed.cy - 1
+		ed.cx = len(ed.rows[prev].chars)
+		ed.rowAppend(prev, ed.rows[ed.cy].chars)
+		ed.delRow(ed.cy)
+		ed.cy = prev
+		ed.clampCursor()
+		return
+	}
+	chars := ed.rows[ed.cy].chars
+	from := ed.cx - 1
+	if ed.cfg.smartTab && ed.cfg.expandTabs && strings.Trim(chars[:ed.cx], " ") == "" {
+		// Only spaces before the cursor, so columns and indices agree.
+		from = (ed.cx - 1) / ed.indentWidth() * ed.indentWidth()
+	}
+	ed.rowDelChars(ed.cy, from, ed.cx)
+	ed.cx = from
+}
+
+// Delete the character under the cursor, or join the next line at the end
+// of the line. Between an empty pair autoclose deletes both sides.
+func (ed *Editor) deleteChar() {
+	if !ed.checkWritable() || ed.numRows() == 0 || ed.deletePair() {
+		return
+	}
+	chars := ed.rows[ed.cy].chars
+	if ed.cx < len(chars) {
+		_, size := utf8.DecodeRuneInString(chars[ed.cx:])
+		ed.rowDelChars(ed.cy, ed.cx, ed.cx+size)
+		return
+	}
+	if ed.cy+1 < ed.numRows() {
+		ed.rowAppend(ed.cy, ed.rows[ed.cy+1].chars)
+		ed.delRow(ed.cy + 1)
+	}
+}
+
+// Indent with Tab: insert a level of indentation at the cursor, or indent
+// the whole line, as tabindent has it. With expandtab the spaces inserted at
+// the cursor reach the next indent stop, so smarttab's Backspace takes them
+// out again in one go.
+func (ed *Editor) tab() {
+	if !ed.checkWritable() {
+		return
+	}
+	if ed.numRows() == 0 {
+		ed.insertRow(0, "")
+	}
+	chars := ed.rows[ed.cy].chars
+	mode := ed.cfg.tabIndent
+	if mode == "line" || (mode == "leading" && ed.cx <= firstNonBlank(chars)) {
+		unit := ed.indentUnit()
+		ed.rowSetChars(ed.cy, unit+chars)
+		ed.cx += len(unit)
+		return
+	}
+	unit := "\t"
+	if ed.cfg.expandTabs {
+		rx := ed.rows[ed.cy].cxToRx(ed.cx, ed.cfg.tabStop)
+		unit = strings.Repeat(" ", ed.indentWidth()-rx%ed.indentWidth())
+	}
+	ed.rowSetChars(ed.cy, chars[:ed.cx]+unit+chars[ed.cx:])
+	ed.cx += len(unit)
+}
+
+// Do the last change made with a key again at the cursor.
+func (ed *Editor) repeatChange() {
+	if ed.lastChange == "" {
+		ed.setStatus("No change to repeat")
+		return
+	}
+	actions[ed.lastChange](ed)
+}
+
+// Replace the text of row at.
+func (ed *Editor) rowSetChars(at int, s string) {
+	ed.recordRows(at, 1, 1)
+	row := &ed.rows[at]
+	row.chars = s
+	// The diagnostic was about what the row said before.
+	row.diag = ""
+	row.update(ed.cfg.tabStop)
+	ed.updateSyntax(at)
+	ed.dirty = true
+}
+
+// Whitespace for one level of indentation.
+func (ed *Editor) indentUnit() string {
+	if ed.cfg.expandTabs {
+		return strings.Repeat(" ", ed.indentWidth())
+	}
+	return "\t"
+}
+
+// Split the line at the cursor, indenting the new line like the current one.
+// In languages with brackets, see Syntax.brackets, a line ending in an opener
+// such as "{" indents the next one a level deeper, and a line holding only
+// closers such as "}" or "});" is first lined up with the opener's line.
+func (ed *Editor) newline() {
+	if !ed.checkWritable() {
+		return
+	}
+	if ed.numRows() == 0 {
+		ed.insertRow(0, "")
+	}
+	brackets := ""
+	if ed.syntax != nil {
+		brackets = ed.syntax.brackets
+	}
+	if closers := bracketClosers(brackets); closers != "" {
+		line := strings.TrimSpace(ed.rows[ed.cy].chars)
+		// Separators may follow, as in "}," or ");".
+		if line != "" && strings.IndexByte(closers, line[0]) >= 0 && strings.Trim(line, closers+",;") == "" {
+			pair := strings.IndexByte(brackets, line[0]) - 1
+			if open := ed.bracketOpener(ed.cy, brackets[pair], line[0]); open >= 0 {
+				indent := ed.rows[open].chars[:firstNonBlank(ed.rows[open].chars)]
+				old := ed.rows[ed.cy].chars
+				ed.rowSetChars(ed.cy, indent+line)
+				ed.cx += len(indent) + len(line) - len(old)
+				if ed.cx < 0 {
+					ed.cx = 0
+				}
+			}
+		}
+	}
+	chars := ed.rows[ed.cy].chars
+	before, after := chars[:ed.cx], strings.TrimLeft(chars[ed.cx:], " \t")
+	indent := chars[:firstNonBlank(chars)]
+	if len(indent) > len(before) {
+		indent = before
+	}
+	inner := indent
+	if last := strings.TrimRight(before, " \t"); last != "" {
+		if pair := strings.IndexByte(brackets, last[len(last)-1]); pair >= 0 && pair%2 == 0 {
+			inner += ed.indentUnit()
+			// Between an opener and its closer the closer goes on a line
+			// of its own.
+			if strings.HasPrefix(after, brackets[pair+1:pair+2]) {
+				ed.insertRow(ed.cy+1, indent+after)
+				after = ""
+			}
+		}
+	}
+	ed.rowSetChars(ed.cy, before)
+	ed.insertRow(ed.cy+1, inner+after)
+	ed.cy++
+	ed.cx = len(inner)
+}
+
+// The closers of the bracket pairs in brackets, see Syntax.brackets.
+func bracketClosers(brackets string) string {
+	var b strings.Builder
+	for i := 1; i < len(brackets); i += 2 {
+		b.WriteByte(brackets[i])
+	}
+	return b.String()
+}
+
+// Row of the open bracket that the last close bracket on row y closes, -1
+// if there is none. Brackets of the pair are counted as they come, strings
+// and comments aren't understood.
+func (ed *Editor) bracketOpener(y int, open, close byte) int {
+	depth := 0
+	for i := y; i >= 0; i-- {
+		chars := ed.rows[i].chars
+		for j := len(chars) - 1; j >= 0; j-- {
+			switch chars[j] {
+			case close:
+				depth++
+			case open:
+				depth--
+				if depth == 0 {
+					return i
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// Strip trailing spaces and tabs from every row.
+func (ed *Editor) trimTrailing() {
+	if !ed.checkWritable() {
+		return
+	}
+	changed := 0
+	for i := range ed.rows {
+		if trimmed := strings.TrimRight(ed.rows[i].chars, " \t"); trimmed != ed.rows[i].chars {
+			ed.rowSetChars(i, trimmed)
+			changed++
+		}
+	}
+	ed.clampCursor()
+	ed.setStatus("Trimmed trailing whitespace from %d lines", changed)
+}
+
+// Insert lines as new rows before row at, in one splice rather than a row
+// at a time.
+func (ed *Editor) insertRows(at int, lines []string) {
+	ed.recordRows(at, 0, len(lines))
+	rows := make([]Row, len(lines))
+	state := ST_NONE
+	if at > 0 {
+		state = ed.rows[at-1].hlState
+	}
+	for i, line := range lines {
+		rows[i] = Row{chars: line, crlf: ed.crlf}
+		rows[i].update(ed.cfg.tabStop)
+		state = rows[i].highlight(state, ed.syntax)
+	}
+	ed.rows = append(ed.rows[:at], append(rows, ed.rows[at:]...)...)
+	if end := at + len(rows); end < len(ed.rows) {
+		ed.updateSyntax(end)
+	}
+	ed.dirty = true
+}
+
+// Insert the contents of filename below the cursor line, like :r in vi. The
+// file is read in full before the buffer is touched, so a read error leaves
+// it unchanged. Inserted lines take the buffer's line ending.
+func (ed *Editor) readFile(filename string) {
+	if !ed.checkWritable() {
+		return
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		ed.fail("Can't read %s: %v", filename, err)
+		return
+	}
+	defer f.Close()
+
+	var lines []string
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		if len(line) > 0 {
+			line = strings.TrimSuffix(line, "\n")
+			lines = append(lines, strings.TrimSuffix(line, "\r"))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			ed.fail("Can't read %s: %v", filename, err)
+			return
+		}
+	}
+	if len(lines) == 0 {
+		ed.setStatus("%s is empty", filename)
+		return
+	}
+	at := ed.cy + 1
+	if ed.numRows() == 0 {
+		at = 0
+	}
+	ed.insertRows(at, lines)
+	ed.cy, ed.cx = at, 0
+	ed.setStatus("Inserted %d lines from %s", len(lines), filename)
+}
@@ -0,0 +1,89 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewlineIndent(t *testing.T) {
+	tests := []struct {
+		name, text, keys, want string
+	}{
+		// An opener indents a level, its closer comes back out to the
+		// opener's line, and other lines keep the indent before them.
+		{"a.go", "", "func f() {\rx := g(\r1,\r2,\r)\rreturn x\r}\r",
+			"func f() {\n\tx := g(\n\t\t1,\n\t\t2,\n\t)\n\treturn x\n}\n\n"},
+		// Between a pair the closer goes on its own line.
+		{"b.go", "if x {}\n", "\x1b[F\x1b[D" + insertKey + "\ry()",
+			"if x {\n\ty()\n}\n"},
+		{"c.js", "f([\n", "\x1b[F" + insertKey + "\r1\r])\r",
+			"f([\n\t1\n])\n\n"},
+		// Without brackets the indent is only copied, openers or not.
+		{"a.py", "", "def f():\r    x = [\r1,\r]\rreturn\r",
+			"def f():\n    x = [\n    1,\n    ]\n    return\n    \n"},
+		{"a.sh", "", "f() {\r  echo (\rdone\r", "f() {\n  echo (\n  done\n  \n"},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.autoClose = false
+		path := writeTemp(t, tt.name, tt.text)
+		keys := tt.keys
+		if tt.text == "" {
+			keys = insertKey + keys
+		}
+		ed, _ := runKeys(t, cfg, path, keys)
+		if got := ed.Contents(); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTabIndent(t *testing.T) {
+	right := func(n int) string { return strings.Repeat("\x1b[C", n) }
+	tests := []struct {
+		mode       string
+		expandTabs bool
+		text, keys string
+		want       string
+		cx         int
+	}{
+		// With spaces, at the cursor Tab goes on to the next indent stop,
+		// in the indent or in the text.
+		{"cursor", true, "    ab", right(2) + "\t", "      ab", 4},
+		{"cursor", true, "    ab", right(5) + "\t", "    a   b", 8},
+		{"line", true, "    ab", right(2) + "\t", "        ab", 6},
+		{"line", true, "    ab", right(5) + "\t", "        ab", 9},
+		{"leading", true, "    ab", right(2) + "\t", "        ab", 6},
+		{"leading", true, "    ab", right(4) + "\t", "        ab", 8},
+		{"leading", true, "    ab", right(5) + "\t", "    a   b", 8},
+		// With tabs a level is one tab wherever it goes.
+		{"cursor", false, "\tab", right(2) + "\t", "\ta\tb", 3},
+		{"line", false, "\tab", right(2) + "\t", "\t\tab", 3},
+		{"leading", false, "\tab", "\t", "\t\tab", 1},
+		{"leading", false, "\tab", right(2) + "\t", "\ta\tb", 3},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.tabIndent, cfg.expandTabs, cfg.shiftWidth = tt.mode, tt.expandTabs, 4
+		path := writeTemp(t, "a.txt", tt.text+"\n")
+		ed, _ := runKeys(t, cfg, path, tt.keys)
+		if got := ed.rows[0].chars; got != tt.want || ed.cx != tt.cx {
+			t.Errorf("%s, expandtab %v, %q: got %q at %d, want %q at %d", tt.mode, tt.expandTabs, tt.keys, got, ed.cx, tt.want, tt.cx)
+		}
+	}
+
+	// smarttab's Backspace takes back the spaces Tab put in, however many.
+	for _, smartTab := range []bool{true, false} {
+		cfg := DefaultConfig()
+		cfg.expandTabs, cfg.shiftWidth, cfg.smartTab = true, 4, smartTab
+		path := writeTemp(t, "a.txt", "    ab\n")
+		ed, _ := runKeys(t, cfg, path, right(4)+"\t\x7f")
+		want := "    ab"
+		if !smartTab {
+			want = "       ab"
+		}
+		if got := ed.rows[0].chars; got != want {
+			t.Errorf("smarttab %v: got %q, want %q", smartTab, got, want)
+		}
+	}
+}
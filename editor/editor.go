@@ -0,0 +1,1024 @@
+// Package editor is exa, a small terminal text editor. Main runs it on the
+// terminal, and New or RunScript make an editor on any reader of keys and
+// writer of frames, e.g. to embed it or to test it.
+package editor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Editor global state. Hold terminal size, cursor and the buffer being edited.
+type Editor struct {
+	width, height int
+	// Rows available for text, i.e. height minus status and message bar.
+	screenrows int
+	// Cursor position. cx is an index into Row.chars, rx into Row.render.
+	cx, cy int
+	rx     int
+	// Scroll offset, i.e. the first row and column shown on screen.
+	rowoff, coloff int
+	rows           []Row
+	// Set instead of rows when the file is too large to load.
+	lazy *lazyFile
+	// The file looks binary and is only viewed.
+	binary bool
+	// Loading the file was cancelled, only the start of it is in rows.
+	partial bool
+	// The file isn't writable by the user, so editing is refused until
+	// forced with ":noreadonly".
+	readOnly bool
+	// Set while the file is shown as a hex dump instead of the buffer.
+	hex *hexView
+	// Set while a directory listing is shown instead of the buffer.
+	browser *dirBrowser
+	// The last search, and whether its matches are marked with hlsearch on.
+	// search is compiled from searchPattern as ignorecase has it, and only
+	// whole words match with searchWords.
+	search        *regexp.Regexp
+	searchPattern string
+	searchWords   bool
+	searchShown   bool
+	// The block keyword under the cursor and its partner, see
+	// findBlockPair, and a copy of a row's hl to mark them in.
+	blockPair []wordSpan
+	pairHl    []uint8
+	// Some row has a diagnostic. Signs for them and line numbers are
+	// shown in a gutter of gutter columns, see gutterWidth.
+	hasDiags bool
+	gutter   int
+	// The file changed on disk after it was read, and the buffer was kept
+	// rather than reloaded. Saving asks first, see confirmOverwrite.
+	diskNewer bool
+	// Columns of the minimap, see minimapWidth.
+	mapWidth int
+	// Set while following the file as it grows, see toggleFollow.
+	follow *time.Ticker
+	// Quits in a row given with unsaved changes, see quit.
+	quitPresses int
+	// Set while command output is shown below the buffer.
+	pane     *outputPane
+	filename string
+	// Whether the buffer changed since it was opened or saved.
+	dirty bool
+	// Line ending of new rows, the one most rows in the file use.
+	crlf bool
+	// The last line of the file has no line ending.
+	noEOL bool
+	// Size and modification time of the file when last read or written,
+	// diskSize -1 if it wasn't on disk.
+	diskSize int64
+	diskTime time.Time
+	// Git branch of the file, "" outside a repository.
+	branch string
+	// Some row was folded, so rows may be hidden.
+	hasFolds bool
+	// File edited before this one and the cursor position it was left at,
+	// for switching back. altFile is "" before the first switch.
+	altFile      string
+	altCy, altCx int
+	// Where tag jumps were made from, the latest last.
+	tagStack []tagPos
+	// Word list for spell checking, loaded on first use. dictErr holds the
+	// spell file that failed to load.
+	dict    *Dictionary
+	dictErr string
+	syntax  *Syntax
+	cfg     *Config
+	// Output of the statuscmd setting.
+	statusHook statusHook
+	// Indentation settings as configured, which each file starts from
+	// before its filetype's style is applied.
+	indentBase struct {
+		tabStop, shiftWidth int
+		expandTabs          bool
+	}
+	// Message shown under the status bar, and when it was set.
+	statusmsg     string
+	statusmsgTime time.Time
+	// Set when a command fails, see fail, for batch mode to stop at.
+	failed bool
+	// Set while prompt reads an answer in the message bar.
+	prompting bool
+	// When the scrolling message is next redrawn, see marquee.
+	marqueeWake time.Time
+	// Previously entered ':' commands, oldest first.
+	cmdHistory []string
+	// Keys typed, read from the terminal in the background.
+	keys *keyReader
+	// Where frames are drawn, and how to ask it for its size.
+	out  io.Writer
+	size func() (width, height int, err error)
+	// Pending count prefix typed before a movement key, 0 if none.
+	count int
+	// The last change made with a key, for "repeat". "" if none yet.
+	lastChange string
+	// Groups of changes undo takes back, the last one last, and those it
+	// took back for redo. The changes of the key being handled go in
+	// undoing until it is done.
+	undoStack, redoStack []undoGroup
+	undoing              *undoGroup
+	// The id of the last group made, the state saved and the state before
+	// the groups kept, see undoState.
+	undoSeq, savedUndo, undoBase int
+	// What was copied or cut, the last first, and the last paste, see put.
+	yanks     []register
+	lastPaste lastPaste
+	// Printable keys type text, replacing what's under the cursor with
+	// overwrite, see toggleInsert.
+	typing, overwrite bool
+	// Cursors besides cy, cx that keys act at too, see addCursor.
+	cursors []cursorPos
+	// Exit status, non-zero when quitting discards changes so that tools
+	// running exa as $EDITOR can tell an aborted edit.
+	exitCode int
+	// Puts the terminal back in the mode it had, set by Main, and whether
+	// resetTerminal did already.
+	restoreTerminal func()
+	terminalReset   bool
+	// Output of the frame being drawn, reused between refreshes.
+	frame   bytes.Buffer
+	linebuf bytes.Buffer
+	// Screen lines of the frame on the terminal and when it was drawn the
+	// width and scroll offset, to redraw only what changed.
+	lastFrame, frameLines  []string
+	lastWidth              int
+	lastRowoff, lastColoff int
+	lastDrawn              time.Time
+}
+
+// A single line of text. render is what gets drawn: chars with tabs expanded.
+type Row struct {
+	chars  string
+	render string
+	// Highlight class of each byte in render.
+	hl []uint8
+	// Highlighter state at the start of the row, that of the row before,
+	// and at its end.
+	hlStart, hlState uint8
+	// Whether the marks only added for rows on screen, such as misspellings
+	// and TODO markers, are in hl.
+	marked bool
+	// Line ends in \r\n rather than \n in the file.
+	crlf bool
+	// Offsets in render of control characters drawn as ^X.
+	ctrl []int
+	// The block indented under the row is folded away.
+	folded bool
+	// Message of a diagnostic reported on the row, see applyDiagnostics.
+	diag string
+}
+
+type EdKey int
+
+// Alias for non-ASCII character.
+// Start with a large to prevent conflict with regular key.
+const (
+	ARW_LEFT EdKey = iota + 1000
+	ARW_UP
+	ARW_RIGHT
+	ARW_DOWN
+	PG_UP
+	PG_DOWN
+	HOME_KEY
+	END_KEY
+	// Arrows with Ctrl held.
+	CTRL_LEFT
+	CTRL_RIGHT
+	INSERT_KEY
+	DEL_KEY
+	// Not a key, the terminal was resized while waiting for one, or the
+	// followed file is due to be checked.
+	RESIZE
+	// Not keys either, the terminal window gained or lost focus. Reported
+	// once enabled with FOCUS_REPORTING_ON.
+	FOCUS_IN
+	FOCUS_OUT
+	// Not a key, text was pasted, see keyReader.pasted.
+	PASTE
+	// Not a key, the editor was told to stop, e.g. by SIGTERM, or its
+	// input ended. Every key read after is a STOP too, so prompts give up
+	// on the way out.
+	STOP
+)
+
+// Ask the terminal to report focus changes, and to stop again.
+const (
+	FOCUS_REPORTING_ON  = "\x1b[?1004h"
+	FOCUS_REPORTING_OFF = "\x1b[?1004l"
+)
+
+// Size assumed when the terminal doesn't report one, e.g. a serial console
+// or a pty that was never given a size.
+const (
+	DEFAULT_WIDTH  = 80
+	DEFAULT_HEIGHT = 24
+)
+
+// Shortest time between frames while keys are still coming in.
+const FRAME_INTERVAL = 16 * time.Millisecond
+
+// Upper bound for a count prefix, so a stray long number can't hang the editor.
+const MAX_COUNT = 10000
+
+// Run exa on the terminal with the command line arguments after the
+// program name, and return the exit status.
+func Main(args []string) int {
+	// Load config before entering raw mode so errors are readable.
+	cfg, err := loadConfig(configPath())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "exa:", err)
+		return 1
+	}
+	if len(args) >= 1 && args[0] == "--batch" {
+		return batchMain(cfg, args[1:])
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Fprintln(os.Stderr, "exa: input and output must be a terminal")
+		return 1
+	}
+	restore, err := setupTerminal()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "exa:", err)
+		return 1
+	}
+	os.Stdout.WriteString(FOCUS_REPORTING_ON + BRACKETED_PASTE_ON)
+
+	ed := New(cfg, os.Stdin, os.Stdout)
+	ed.restoreTerminal = restore
+	// Run resets the terminal on the way out, this is for returning
+	// early or a panic.
+	defer ed.resetTerminal()
+	ed.keys.resize = resizeSignal()
+	ed.keys.stop = stopSignal()
+	if len(args) >= 1 {
+		filename, line, col := parseFileArg(args[0])
+		if err := ed.openArg(filename); err != nil {
+			ed.resetTerminal()
+			fmt.Fprintln(os.Stderr, "exa:", err)
+			return 1
+		}
+		ed.jumpTo(line, col)
+		if line == 0 {
+			ed.restorePosition()
+		}
+	}
+
+	return ed.Run()
+}
+
+// Make an editor with an empty buffer reading keys from in and drawing to
+// out, a terminal in raw mode or anything that acts like one. Open a file
+// with open, then run it.
+func New(cfg *Config, in io.Reader, out io.Writer) *Editor {
+	ed := &Editor{
+		diskSize: -1,
+		cfg:      cfg,
+		keys:     newKeyReader(in, nil),
+		out:      out,
+		size:     terminalSize,
+	}
+	// What files without a filetype style of their own are indented with.
+	ed.indentBase.tabStop, ed.indentBase.expandTabs, ed.indentBase.shiftWidth = cfg.tabStop, cfg.expandTabs, cfg.shiftWidth
+	ed.updateSize()
+	ed.setStatus("HELP: %s", ed.hintText(ed.keyHints()[:2], -1))
+	ed.showConfigWarnings()
+	return ed
+}
+
+// Open filename, empty for none, and run the editor on the keys read from
+// script, e.g. a test or an automated edit. Frames are drawn to out at the
+// default size. When the script ends the editor quits as if the terminal
+// went away, so it always runs to completion. Return the editor with its
+// final buffer and the exit status.
+func RunScript(cfg *Config, filename string, script io.Reader, out io.Writer) (*Editor, int, error) {
+	ed := New(cfg, script, out)
+	ed.size = func() (int, int, error) { return DEFAULT_WIDTH, DEFAULT_HEIGHT, nil }
+	ed.updateSize()
+	if filename != "" {
+		if err := ed.openArg(filename); err != nil {
+			return nil, 1, err
+		}
+	}
+	return ed, ed.Run(), nil
+}
+
+// Open the file the editor was started on. A directory is listed in the
+// browser to pick a file from, and a file that doesn't exist yet gives an
+// empty buffer to be saved under its name. Devices and sockets are refused,
+// a named pipe, e.g. from <(command), is read like a file.
+func (ed *Editor) openArg(filename string) error {
+	fi, err := os.Stat(filename)
+	switch {
+	case os.IsNotExist(err):
+		ed.filename = filename
+		ed.branch = gitBranch(filename)
+		ed.selectSyntax()
+		return nil
+	case err != nil:
+		return err
+	case fi.IsDir():
+		ed.browseDir(filename)
+		if ed.browser == nil {
+			// browseDir said why in the status bar.
+			return fmt.Errorf("can't read directory %s", filename)
+		}
+		return nil
+	case fi.Mode()&(os.ModeDevice|os.ModeCharDevice|os.ModeSocket|os.ModeIrregular) != 0:
+		return fmt.Errorf("%s is not a regular file or a directory", filename)
+	}
+	open, lazy := ed.confirmSize(filename)
+	if !open {
+		return fmt.Errorf("not opening %s", filename)
+	}
+	return ed.open(filename, lazy)
+}
+
+// Edit until the user quits and return the exit status. The buffer is left
+// as it was last, see Contents and Dirty.
+func (ed *Editor) Run() int {
+	for run := true; run; {
+		// Keys that came in a burst, e.g. a held arrow key or a paste,
+		// are handled before drawing, but a frame still goes out every
+		// FRAME_INTERVAL so the screen follows along.
+		if !ed.keys.waiting() || time.Since(ed.lastDrawn) >= FRAME_INTERVAL {
+			if ed.follow != nil {
+				ed.followFile()
+			}
+			if ed.hex == nil && ed.browser == nil {
+				ed.checkDiskChange()
+			}
+			ed.updateStatusHook(false)
+			ed.refresh()
+		}
+		run = ed.processKeyPress()
+	}
+	ed.savePosition()
+	ed.saveFolds()
+	ed.resetTerminal()
+	return ed.exitCode
+}
+
+// Put the terminal back as the editor found it, whether it quit or was
+// stopped by a signal: focus reporting and bracketed paste off, or the shell
+// gets the reports, then out of raw mode. Only the first call does
+// anything, so an early reset leaves nothing for a deferred one.
+func (ed *Editor) resetTerminal() {
+	if ed.terminalReset {
+		return
+	}
+	ed.terminalReset = true
+	io.WriteString(ed.out, FOCUS_REPORTING_OFF+BRACKETED_PASTE_OFF)
+	if ed.restoreTerminal != nil {
+		ed.restoreTerminal()
+	}
+}
+
+// Query the terminal size. A terminal can report 0x0 or fail to answer;
+// keep the size known so far then, or the default on startup, and try again
+// on the next redraw.
+func (ed *Editor) updateSize() {
+	width, height, err := ed.size()
+	if err != nil || width <= 0 || height <= 0 {
+		if ed.width > 0 {
+			return
+		}
+		width, height = DEFAULT_WIDTH, DEFAULT_HEIGHT
+	}
+	ed.width, ed.height = width, height
+	// Status bar and message area take a row or more, leave at least one
+	// for text.
+	ed.screenrows = clamp(height-1-ed.msgRows()-ed.paneRows(), 1, height)
+}
+
+// Handle keypress event
+func (ed *Editor) processKeyPress() bool {
+	ch := ed.keys.readKey()
+	switch ch {
+	case RESIZE:
+		// Nothing to do but redraw, which picks up the new size.
+		return true
+	case FOCUS_IN:
+		// Back from another window, which may have drawn over the screen
+		// or changed the file. The redraw checks the file first.
+		ed.invalidateFrame()
+		return true
+	case FOCUS_OUT:
+		if ed.cfg.autoSave && ed.dirty && ed.filename != "" {
+			ed.write(nil)
+		}
+		return true
+	case STOP:
+		// Unsaved changes are lost unless autosave keeps them, there is
+		// no one to ask.
+		if ed.cfg.autoSave && ed.dirty && ed.filename != "" {
+			ed.write(nil)
+		}
+		return ed.leave()
+	}
+	// Any key other than another quit starts the quit count over.
+	presses := ed.quitPresses
+	// What the key changes is undone in one go, see endUndo.
+	typed := false
+	ed.beginUndo()
+	defer func() {
+		ed.endUndo(typed)
+		if ed.quitPresses == presses {
+			ed.quitPresses = 0
+		}
+	}()
+	if ed.browser != nil {
+		return ed.browserKeyPress(ch)
+	}
+	if ed.hex != nil {
+		return ed.hexKeyPress(ch)
+	}
+	if ed.pane != nil && ed.pane.focused {
+		return ed.paneKeyPress(ch)
+	}
+	if ch == PASTE {
+		ed.cursors = nil
+		ed.paste(ed.keys.pasted)
+		return true
+	}
+	// Escape goes back to a single cursor, then stops typing.
+	if ch == 0x1b && len(ed.cursors) > 0 {
+		ed.cursors = nil
+		return true
+	}
+	if ed.typing {
+		switch {
+		case ch == 0x1b:
+			ed.typing, ed.overwrite = false, false
+			return true
+		case isTypedChar(ch):
+			typed = true
+			r := ed.keys.readRune(byte(ch))
+			ed.atEachCursor(true, func() bool { ed.typeChar(r); return true })
+			return true
+		}
+	}
+	// Digits build up a count prefix, vim-style. A leading 0 is not a count.
+	if ch >= '0' && ch <= '9' && (ch != '0' || ed.count > 0) {
+		ed.count = ed.count*10 + int(ch-'0')
+		if ed.count > MAX_COUNT {
+			ed.count = MAX_COUNT
+		}
+		return true
+	}
+	count := ed.count
+	ed.count = 0
+	// Unbound keys, control characters included, are ignored.
+	action, ok := ed.cfg.keymap[ch]
+	if !ok {
+		return true
+	}
+	// Only movements and repeated changes take a count, any other key
+	// just drops it.
+	if !(movements[action] || action == "repeat") || count == 0 {
+		count = 1
+	}
+	if !multiCursor[action] && action != "addcursor" && action != "insert" {
+		ed.cursors = nil
+	}
+	for i := 0; i < count; i++ {
+		run := func() bool { return actions[action](ed) }
+		if multiCursor[action] {
+			// Still a single one unless addcursor added more.
+			if !ed.atEachCursor(changes[action], run) {
+				return false
+			}
+		} else if !run() {
+			return false
+		}
+	}
+	if changes[action] {
+		ed.lastChange = action
+	}
+	return true
+}
+
+// Split a "file:line" or "file:line:col" argument as emitted by compilers and
+// grep. Line and column are 1-based, 0 when absent. If a file with the colon
+// in its name actually exists the argument is taken literally.
+func parseFileArg(arg string) (filename string, line, col int) {
+	if _, err := os.Stat(arg); err == nil {
+		return arg, 0, 0
+	}
+	filename = arg
+	// Peel off at most two trailing numeric fields, col first.
+	var nums []int
+	for i := 0; i < 2; i++ {
+		idx := strings.LastIndexByte(filename, ':')
+		if idx < 0 {
+			break
+		}
+		n, err := strconv.Atoi(filename[idx+1:])
+		if err != nil || n < 1 {
+			break
+		}
+		nums = append([]int{n}, nums...)
+		filename = filename[:idx]
+	}
+	switch len(nums) {
+	case 1:
+		line = nums[0]
+	case 2:
+		line, col = nums[0], nums[1]
+	}
+	return filename, line, col
+}
+
+// Read a file into the buffer, one row per line. Line terminators are not
+// kept in the row. Files above the largefile size, and any file with lazy
+// set, are indexed and viewed read-only instead.
+func (ed *Editor) open(filename string, lazy bool) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ed.filename = filename
+	ed.branch = gitBranch(filename)
+	ed.recordDiskState()
+	ed.readOnly = readOnlyFile(filename)
+	// A binary file would come back mangled from a text buffer, so it's
+	// opened for viewing only.
+	head := make([]byte, BINARY_SNIFF_BYTES)
+	n, _ := f.ReadAt(head, 0)
+	if ed.binary = isBinary(head[:n]); ed.binary {
+		defer ed.setStatus("Binary file, opened read-only")
+	}
+	var size int64
+	if fi, err := f.Stat(); err == nil {
+		size = fi.Size()
+	}
+	// Large files can take a while. Escape stops loading and leaves what
+	// was read so far to look at.
+	progress := func(done int64) bool { return ed.loadProgress(done, size) }
+	if size > ed.cfg.largeFile || lazy {
+		ed.lazy, err = openLazy(filename, progress)
+		if err != nil && err != errCancelled {
+			return err
+		}
+		ed.selectSyntax()
+		ed.detectIndent()
+		ed.applyModelines()
+		if err == errCancelled {
+			ed.setStatus("Loading cancelled, showing the first %d lines read-only", ed.numRows())
+		} else if lazy {
+			ed.setStatus("Opened read-only")
+		} else {
+			ed.setStatus("File is too large to edit, opened read-only")
+		}
+		return nil
+	}
+	err = ed.readRows(&progressReader{ed: ed, r: f, size: size})
+	if err != nil && err != errCancelled {
+		return err
+	}
+	ed.selectSyntax()
+	ed.detectIndent()
+	ed.applyModelines()
+	ed.detectLineEndings()
+	if err == errCancelled {
+		ed.partial = true
+		ed.setStatus("Loading cancelled, showing the first %d lines read-only", ed.numRows())
+	}
+	ed.restoreFolds()
+	ed.restoreUndo()
+	return nil
+}
+
+// Drop the buffer to make way for another file.
+func (ed *Editor) closeBuffer() {
+	if ed.hex != nil {
+		ed.closeHex()
+	}
+	if ed.lazy != nil {
+		ed.lazy.close()
+		ed.lazy = nil
+	}
+	ed.rows = nil
+	ed.clearUndo()
+	ed.filename, ed.branch, ed.syntax = "", "", nil
+	ed.diskSize, ed.diskNewer = -1, false
+	ed.dirty, ed.crlf, ed.noEOL, ed.hasFolds = false, false, false, false
+	ed.binary, ed.readOnly, ed.partial, ed.hasDiags = false, false, false, false
+	ed.cx, ed.cy, ed.rowoff, ed.coloff = 0, 0, 0, 0
+}
+
+// Append the lines read from r as rows, keeping their line endings.
+func (ed *Editor) readRows(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			// Only the last line can lack a newline, remember it so that
+			// saving writes the file back exactly as it was.
+			ed.noEOL = !strings.HasSuffix(line, "\n")
+			line = strings.TrimSuffix(line, "\n")
+			crlf := strings.HasSuffix(line, "\r")
+			ed.appendRow(strings.TrimSuffix(line, "\r"), crlf)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Write the buffer to filename, one row per line, each with the line ending
+// it was read with. The last line only gets one if it had one, or the
+// finalnewline setting asks for it.
+func (ed *Editor) save(filename string) (int, error) {
+	data := ed.Contents()
+	if err := writeFileAtomic(filename, []byte(data), ed.cfg.followSymlinks); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// Whether the buffer has changes not saved to its file.
+func (ed *Editor) Dirty() bool {
+	return ed.dirty
+}
+
+// Name of the file being edited, "" for a buffer never saved.
+func (ed *Editor) Filename() string {
+	return ed.filename
+}
+
+// The buffer as it would be written to disk.
+func (ed *Editor) Contents() string {
+	var b strings.Builder
+	for i, row := range ed.rows {
+		b.WriteString(row.chars)
+		if i == len(ed.rows)-1 && ed.noEOL && !ed.cfg.finalNewline {
+			break
+		}
+		if row.crlf {
+			b.WriteByte('\r')
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func (ed *Editor) numRows() int {
+	if ed.lazy != nil {
+		return ed.lazy.numRows()
+	}
+	return len(ed.rows)
+}
+
+// Return row i, which must be in range.
+func (ed *Editor) row(i int) *Row {
+	if ed.lazy != nil {
+		return ed.lazy.row(i, ed.cfg.tabStop)
+	}
+	return &ed.rows[i]
+}
+
+func (ed *Editor) appendRow(s string, crlf bool) {
+	row := Row{chars: s, crlf: crlf}
+	row.update(ed.cfg.tabStop)
+	state := ST_NONE
+	if len(ed.rows) > 0 {
+		state = ed.rows[len(ed.rows)-1].hlState
+	}
+	row.highlight(state, ed.syntax)
+	ed.rows = append(ed.rows, row)
+}
+
+// Insert a new row before row at, at == numRows() appends.
+func (ed *Editor) insertRow(at int, s string) {
+	ed.recordRows(at, 0, 1)
+	ed.rows = append(ed.rows, Row{})
+	copy(ed.rows[at+1:], ed.rows[at:])
+	ed.rows[at] = Row{chars: s, crlf: ed.crlf}
+	ed.rows[at].update(ed.cfg.tabStop)
+	ed.updateSyntax(at)
+	ed.dirty = true
+}
+
+func (ed *Editor) delRow(at int) {
+	ed.recordRows(at, 1, 0)
+	copy(ed.rows[at:], ed.rows[at+1:])
+	ed.rows = ed.rows[:len(ed.rows)-1]
+	if at < len(ed.rows) {
+		ed.updateSyntax(at)
+	}
+	ed.dirty = true
+}
+
+// Remove chars[from:to] from row at.
+func (ed *Editor) rowDelChars(at, from, to int) {
+	ed.recordRows(at, 1, 1)
+	row := &ed.rows[at]
+	row.chars = row.chars[:from] + row.chars[to:]
+	row.update(ed.cfg.tabStop)
+	ed.updateSyntax(at)
+	ed.dirty = true
+}
+
+// Report in the status line when the buffer can't be changed.
+func (ed *Editor) checkWritable() bool {
+	if ed.lazy != nil {
+		ed.setStatus("File is too large to edit, opened read-only")
+		return false
+	}
+	if ed.binary {
+		ed.setStatus("Binary file, opened read-only")
+		return false
+	}
+	if ed.partial {
+		ed.setStatus("File is only partly loaded, opened read-only")
+		return false
+	}
+	if ed.readOnly {
+		ed.setStatus("File is read-only (:noreadonly to edit anyway)")
+		return false
+	}
+	if ed.follow != nil {
+		ed.setStatus("Following the file (:follow to stop and edit)")
+		return false
+	}
+	return true
+}
+
+// Re-render every row, e.g. after the tab stop changed.
+func (ed *Editor) updateRows() {
+	if ed.lazy != nil {
+		ed.lazy.flush()
+	}
+	state := ST_NONE
+	for i := range ed.rows {
+		ed.rows[i].update(ed.cfg.tabStop)
+		state = ed.rows[i].highlight(state, ed.syntax)
+	}
+}
+
+// Rebuild the render string of the row from its chars.
+// Control characters are shown in caret notation like less does, e.g. ^G for
+// \x07, so they can't garble the terminal. chars keeps the byte itself.
+func (row *Row) update(tabStop int) {
+	var b strings.Builder
+	row.ctrl = row.ctrl[:0]
+	for i := 0; i < len(row.chars); i++ {
+		c := row.chars[i]
+		switch {
+		case c == '\t':
+			// Pad with spaces up to the next tab stop.
+			b.WriteByte(' ')
+			for b.Len()%tabStop != 0 {
+				b.WriteByte(' ')
+			}
+		case isControl(c):
+			row.ctrl = append(row.ctrl, b.Len())
+			b.WriteByte('^')
+			// DEL (127) is ^?, the rest map onto @, A-Z, [ \ ] ^ _
+			b.WriteByte(c ^ 0x40)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	row.render = b.String()
+}
+
+// Whether c is a control character other than tab.
+func isControl(c byte) bool {
+	return (c < 32 && c != '\t') || c == 127
+}
+
+// Convert an index into chars to the matching index into render.
+func (row *Row) cxToRx(cx, tabStop int) int {
+	rx := 0
+	for i := 0; i < cx && i < len(row.chars); i++ {
+		if row.chars[i] == '\t' {
+			rx += (tabStop - 1) - (rx % tabStop)
+		} else if isControl(row.chars[i]) {
+			// Drawn as two characters.
+			rx++
+		}
+		rx++
+	}
+	return rx
+}
+
+// Place the cursor on a 1-based line and column, clamped to the buffer, and
+// scroll so the line is centered on screen, and the column brought into view.
+// A zero line or column means the start of the buffer or line.
+func (ed *Editor) jumpTo(line, col int) {
+	if ed.numRows() == 0 {
+		return
+	}
+	ed.cy, ed.cx = line-1, col-1
+	ed.clampCursor()
+	ed.scrollCursorTo(ed.screenrows / 2)
+	// A column off screen is brought to the middle too.
+	rx, width := ed.row(ed.cy).cxToRx(ed.cx, ed.cfg.tabStop), ed.textWidth()
+	if rx < ed.coloff || rx >= ed.coloff+width {
+		ed.coloff = clamp(rx-width/2, 0, rx)
+	}
+}
+
+// Scroll so the cursor row is y lines from the top of the screen, as far as
+// that doesn't scroll past either end of the file.
+func (ed *Editor) scrollCursorTo(y int) {
+	if ed.numRows() == 0 {
+		return
+	}
+	ed.rowoff = ed.linesUp(ed.cy, y)
+	// Keep the screen full down to the last row.
+	if last := ed.linesUp(ed.numRows()-1, ed.screenrows-1); ed.rowoff > last {
+		ed.rowoff = last
+	}
+}
+
+// v limited to lo..hi. Screen sizes can make hi come out below lo, lo
+// wins then so e.g. a width never goes negative.
+func clamp(v, lo, hi int) int {
+	if v > hi {
+		v = hi
+	}
+	if v < lo {
+		v = lo
+	}
+	return v
+}
+
+// Columns of the screen rows of text get, between the gutter and the
+// minimap.
+func (ed *Editor) textWidth() int {
+	return clamp(ed.width-ed.gutter-ed.mapWidth, 1, ed.width)
+}
+
+// Lines kept in view around the cursor, scrolloff as far as the screen
+// allows.
+func (ed *Editor) scrollMargin() int {
+	return clamp(ed.cfg.scrollOff, 0, (ed.screenrows-1)/2)
+}
+
+// Scroll a screen down, or up, keeping pageoverlap lines of the old screen
+// in view. The cursor goes to the top of the new screen, or the bottom when
+// paging up, or to the first or last row when the screen can't move on.
+func (ed *Editor) page(up bool) {
+	if ed.numRows() == 0 {
+		return
+	}
+	step := ed.screenrows - ed.cfg.pageOverlap
+	if step < 1 {
+		step = 1
+	}
+	margin := ed.scrollMargin()
+	if up {
+		if ed.rowoff == 0 {
+			ed.cy = 0
+		} else {
+			ed.rowoff = ed.linesUp(ed.rowoff, step)
+			ed.cy = ed.linesDown(ed.rowoff, ed.screenrows-1-margin)
+		}
+	} else {
+		// The offset with the last row at the bottom of the screen.
+		end := ed.linesUp(ed.numRows()-1, ed.screenrows-1)
+		if ed.rowoff >= end {
+			ed.cy = ed.numRows() - 1
+		} else {
+			ed.rowoff = ed.linesDown(ed.rowoff, step)
+			if ed.rowoff > end {
+				ed.rowoff = end
+			}
+			ed.cy = ed.linesDown(ed.rowoff, margin)
+		}
+	}
+	ed.clampCursor()
+}
+
+// The row n lines below row y on screen, or the last row.
+func (ed *Editor) linesDown(y, n int) int {
+	for i := 0; i < n && ed.foldEnd(y) < ed.numRows()-1; i++ {
+		y = ed.foldEnd(y) + 1
+	}
+	return y
+}
+
+// The row n lines above row y on screen, with folds taking one line.
+func (ed *Editor) linesUp(y, n int) int {
+	y = ed.foldHeader(y)
+	for i := 0; i < n && y > 0; i++ {
+		y = ed.foldHeader(y - 1)
+	}
+	return y
+}
+
+// Keep the cursor on an existing row and column, and the scroll offsets in
+// range, after anything that moves the cursor or shrinks the buffer (deleted
+// rows, a shorter line, a smaller reloaded file).
+func (ed *Editor) clampCursor() {
+	n := ed.numRows()
+	if ed.cy >= n {
+		ed.cy = n - 1
+	}
+	if ed.cy < 0 {
+		ed.cy = 0
+	}
+	// Rows hidden in a fold are stood in for by its header.
+	ed.cy = ed.foldHeader(ed.cy)
+	if ed.cx < 0 {
+		ed.cx = 0
+	}
+	if n == 0 {
+		ed.cx = 0
+	} else if l := len(ed.row(ed.cy).chars); ed.cx > l {
+		ed.cx = l
+	}
+	if ed.rowoff >= n {
+		ed.rowoff = n - 1
+	}
+	if ed.rowoff < 0 {
+		ed.rowoff = 0
+	}
+	ed.rowoff = ed.foldHeader(ed.rowoff)
+	if ed.coloff < 0 {
+		ed.coloff = 0
+	}
+}
+
+// Adjust the scroll offset so the cursor is inside the visible window.
+func (ed *Editor) scroll() {
+	ed.clampCursor()
+	ed.rx = 0
+	if ed.cy < ed.numRows() {
+		ed.rx = ed.row(ed.cy).cxToRx(ed.cx, ed.cfg.tabStop)
+	}
+	margin := ed.scrollMargin()
+	if top := ed.linesUp(ed.cy, margin); top < ed.rowoff {
+		ed.rowoff = top
+	}
+	if bottom := ed.linesDown(ed.cy, margin); ed.visibleLines(ed.rowoff, bottom, ed.screenrows) >= ed.screenrows {
+		// Put the bottom of the margin on the last line.
+		ed.rowoff = ed.linesUp(bottom, ed.screenrows-1)
+	}
+	if ed.rx < ed.coloff {
+		ed.coloff = ed.rx
+	}
+	if ed.rx >= ed.coloff+ed.textWidth() {
+		ed.coloff = ed.rx - ed.textWidth() + 1
+	}
+}
+
+func (ed *Editor) moveCursor(ch EdKey) {
+	if ed.numRows() == 0 {
+		return
+	}
+	switch ch {
+	case ARW_LEFT:
+		if ed.cx == 0 {
+			return
+		}
+		ed.cx--
+	case ARW_RIGHT:
+		if ed.cx >= len(ed.row(ed.cy).chars) {
+			return
+		}
+		ed.cx++
+	case ARW_UP:
+		if ed.cy == 0 {
+			return
+		}
+		// A fold counts as a single line.
+		ed.cy = ed.foldHeader(ed.cy - 1)
+	case ARW_DOWN:
+		if ed.foldEnd(ed.cy) >= ed.numRows()-1 {
+			return
+		}
+		ed.cy = ed.foldEnd(ed.cy) + 1
+	case HOME_KEY:
+		// Smart Home goes to the first non-blank character, and from there
+		// on to column 0.
+		first := firstNonBlank(ed.row(ed.cy).chars)
+		if ed.cfg.smartHome && ed.cx != first {
+			ed.cx = first
+		} else {
+			ed.cx = 0
+		}
+	case END_KEY:
+		ed.cx = len(ed.row(ed.cy).chars)
+	}
+	// Snap cursor to the end of line when moving onto a shorter one.
+	ed.clampCursor()
+}
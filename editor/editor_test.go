@@ -0,0 +1,285 @@
+package editor
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Run the editor on keys until they run out, failing the test if it hangs.
+func runKeys(t *testing.T, cfg *Config, filename, keys string) (*Editor, int) {
+	t.Helper()
+	type result struct {
+		ed   *Editor
+		code int
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		ed, code, err := RunScript(cfg, filename, strings.NewReader(keys), ioutil.Discard)
+		done <- result{ed, code, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("RunScript: %v", r.err)
+		}
+		return r.ed, r.code
+	case <-time.After(10 * time.Second):
+		t.Fatalf("editor still running after keys %q", keys)
+	}
+	return nil, 0
+}
+
+// Write a file into a fresh temporary directory and return its path.
+func writeTemp(t testing.TB, name, text string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "exa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunScriptStopsWhenKeysRunOut(t *testing.T) {
+	// A dirty buffer without a name used to swallow the quits it was sent.
+	ed, code := runKeys(t, DefaultConfig(), "", "\r")
+	if code != 1 {
+		t.Errorf("exit status %d with unsaved changes, want 1", code)
+	}
+	if !ed.Dirty() || ed.numRows() != 2 {
+		t.Errorf("dirty %v with %d rows, want the split line kept", ed.Dirty(), ed.numRows())
+	}
+
+	_, code = runKeys(t, DefaultConfig(), writeTemp(t, "a.txt", "a\n"), "jk")
+	if code != 0 {
+		t.Errorf("exit status %d without changes, want 0", code)
+	}
+}
+
+func TestTrailingNewlineRoundTrip(t *testing.T) {
+	tests := []struct {
+		name, text   string
+		finalNewline bool
+		rows         int
+		saved        string
+	}{
+		{"without", "a\nb", false, 2, "xa\nb"},
+		{"with", "a\nb\n", false, 2, "xa\nb\n"},
+		{"empty last line", "a\nb\n\n", false, 3, "xa\nb\n\n"},
+		{"added by finalnewline", "a\nb", true, 2, "xa\nb\n"},
+		{"crlf without", "a\r\nb", false, 2, "xa\r\nb"},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.finalNewline = tt.finalNewline
+		path := writeTemp(t, "a.txt", tt.text)
+		ed, _ := runKeys(t, cfg, path, insertKey+"x\x1b:w\r")
+		if ed.numRows() != tt.rows {
+			t.Errorf("%s: %d rows, want %d", tt.name, ed.numRows(), tt.rows)
+		}
+		if got, _ := ioutil.ReadFile(path); string(got) != tt.saved {
+			t.Errorf("%s: saved %q, want %q", tt.name, got, tt.saved)
+		}
+	}
+}
+
+func TestCursorClampedWhenRowsGo(t *testing.T) {
+	text := "one\ntwo\nthree long line\n"
+	// Backspace at the start of the last line joins it onto the one above.
+	ed, _ := runKeys(t, DefaultConfig(), writeTemp(t, "a.txt", text), ":3\r\x7f")
+	if ed.cy != 1 || ed.cx != 3 {
+		t.Errorf("cursor at %d:%d after joining the last line, want 1:3", ed.cy, ed.cx)
+	}
+
+	// A filter leaving fewer lines.
+	ed, _ = runKeys(t, DefaultConfig(), writeTemp(t, "a.txt", text), ":3\r\x1b[F:!head -n 1\r")
+	if ed.numRows() != 1 || ed.cy != 0 || ed.cx > len("one") {
+		t.Errorf("cursor at %d:%d in %d rows after filtering, want it on the row left", ed.cy, ed.cx, ed.numRows())
+	}
+
+	// Reloading a file that got shorter.
+	path := writeTemp(t, "a.txt", text)
+	ed, _ = runKeys(t, DefaultConfig(), path, ":3\r\x1b[F")
+	ed.rowoff = 2
+	if err := ioutil.WriteFile(path, []byte("x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ed.reload()
+	if ed.cy != 0 || ed.cx != 1 || ed.rowoff != 0 {
+		t.Errorf("cursor at %d:%d scrolled to %d after reloading, want 0:1 at 0", ed.cy, ed.cx, ed.rowoff)
+	}
+	ed.refresh()
+
+	// All of it gone.
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	ed.reload()
+	if ed.cy != 0 || ed.cx != 0 {
+		t.Errorf("cursor at %d:%d in an empty buffer, want 0:0", ed.cy, ed.cx)
+	}
+	ed.refresh()
+}
+
+func TestZeroTerminalSize(t *testing.T) {
+	ed := New(DefaultConfig(), strings.NewReader(""), ioutil.Discard)
+	ed.size = func() (int, int, error) { return 0, 0, nil }
+	ed.updateSize()
+	if ed.width != DEFAULT_WIDTH || ed.height != DEFAULT_HEIGHT || ed.screenrows < 1 {
+		t.Errorf("size %dx%d with %d text rows, want %dx%d", ed.width, ed.height, ed.screenrows, DEFAULT_WIDTH, DEFAULT_HEIGHT)
+	}
+	ed.refresh()
+
+	// A real size once the terminal has one, e.g. on the first resize.
+	ed.size = func() (int, int, error) { return 100, 30, nil }
+	ed.updateSize()
+	if ed.width != 100 || ed.height != 30 {
+		t.Errorf("size %dx%d after a resize, want 100x30", ed.width, ed.height)
+	}
+	// Then losing it again keeps the last good one.
+	ed.size = func() (int, int, error) { return -1, 0, nil }
+	ed.updateSize()
+	if ed.width != 100 || ed.height != 30 {
+		t.Errorf("size %dx%d after a bad size, want 100x30 kept", ed.width, ed.height)
+	}
+}
+
+func TestMainWithoutTerminal(t *testing.T) {
+	if term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
+		t.Skip("running on a terminal")
+	}
+	// No config file of the user's.
+	old, had := os.LookupEnv("XDG_CONFIG_HOME")
+	os.Setenv("XDG_CONFIG_HOME", filepath.Dir(writeTemp(t, "config", "")))
+	defer func() {
+		if had {
+			os.Setenv("XDG_CONFIG_HOME", old)
+		} else {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		}
+	}()
+	if code := Main(nil); code != 1 {
+		t.Errorf("exit status %d without a terminal, want 1", code)
+	}
+}
+
+func TestRenderColumns(t *testing.T) {
+	// Tabs at the start, after spaces and after text, and a space after a
+	// tab.
+	row := Row{chars: "\t  a\tb \tc"}
+	tests := []struct {
+		tabStop int
+		render  string
+		// Render column of each index of chars, and of its end.
+		rx []int
+	}{
+		{8, "          a     b       c", []int{0, 8, 9, 10, 11, 16, 17, 18, 24, 25}},
+		{4, "      a b   c", []int{0, 4, 5, 6, 7, 8, 9, 10, 12, 13}},
+	}
+	for _, tt := range tests {
+		row.update(tt.tabStop)
+		if row.render != tt.render {
+			t.Errorf("tabstop %d: rendered %q, want %q", tt.tabStop, row.render, tt.render)
+		}
+		for cx, want := range tt.rx {
+			if got := row.cxToRx(cx, tt.tabStop); got != want {
+				t.Errorf("tabstop %d: index %d at column %d, want %d", tt.tabStop, cx, got, want)
+			}
+		}
+	}
+}
+
+func TestShiftWidthApartFromTabStop(t *testing.T) {
+	// Tabs drawn 8 wide in a file indented by 4 spaces, the bytes as they
+	// are.
+	path := writeTemp(t, "a.txt", "x\n    y\n        w\nz\tq\n")
+	ed, _ := runKeys(t, DefaultConfig(), path, "\t")
+	if ed.cfg.tabStop != 8 || ed.indentWidth() != 4 || !ed.cfg.expandTabs {
+		t.Errorf("tabstop %d, indent width %d, expandtab %v, want 8, 4, true", ed.cfg.tabStop, ed.indentWidth(), ed.cfg.expandTabs)
+	}
+	if got, want := ed.Contents(), "    x\n    y\n        w\nz\tq\n"; got != want {
+		t.Errorf("buffer %q after Tab, want %q", got, want)
+	}
+	if got := ed.row(3).render; got != "z       q" {
+		t.Errorf("tab rendered as %q, want 8 wide", got)
+	}
+
+	// The same from a modeline.
+	path = writeTemp(t, "b.txt", "\tz\n# vim: ts=4 sw=2 et\n")
+	ed, _ = runKeys(t, DefaultConfig(), path, "\x1b[B\t")
+	if ed.cfg.tabStop != 4 || ed.indentWidth() != 2 {
+		t.Errorf("tabstop %d, indent width %d from the modeline, want 4, 2", ed.cfg.tabStop, ed.indentWidth())
+	}
+	if got := ed.row(0).render; got != "    z" {
+		t.Errorf("tab rendered as %q, want 4 wide", got)
+	}
+	if got := ed.row(1).chars; got != "  # vim: ts=4 sw=2 et" {
+		t.Errorf("line %q after Tab, want 2 spaces added", got)
+	}
+}
+
+func TestStopSignal(t *testing.T) {
+	for _, autoSave := range []bool{false, true} {
+		path := writeTemp(t, "a.txt", "text\n")
+		cfg := DefaultConfig()
+		cfg.autoSave = autoSave
+		// No keys come, as when the terminal is idle and the editor is
+		// killed.
+		in, _ := io.Pipe()
+		var out bytes.Buffer
+		ed := New(cfg, in, &out)
+		ed.size = func() (int, int, error) { return DEFAULT_WIDTH, DEFAULT_HEIGHT, nil }
+		ed.updateSize()
+		if err := ed.openArg(path); err != nil {
+			t.Fatal(err)
+		}
+		ed.insertChar('x')
+		restored := 0
+		ed.restoreTerminal = func() { restored++ }
+		stop := make(chan os.Signal, 1)
+		ed.keys.stop = stop
+		done := make(chan int, 1)
+		go func() { done <- ed.Run() }()
+		stop <- os.Interrupt
+		var code int
+		select {
+		case code = <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("editor still running after a stop signal")
+		}
+		// The screen is cleared on the way out and the terminal reset
+		// once, as after quitting.
+		ed.resetTerminal()
+		reset := "\x1b[H\x1b[2J" + FOCUS_REPORTING_OFF + BRACKETED_PASTE_OFF
+		if end := out.String()[clamp(out.Len()-len(reset)-10, 0, out.Len()):]; !strings.HasSuffix(end, reset) {
+			t.Errorf("autosave %v: output ends %q, want %q", autoSave, end, reset)
+		}
+		if restored != 1 {
+			t.Errorf("autosave %v: terminal restored %d times, want once", autoSave, restored)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, wantCode := "text\n", 1
+		if autoSave {
+			want, wantCode = "xtext\n", 0
+		}
+		if string(data) != want || code != wantCode {
+			t.Errorf("autosave %v: file %q, exit status %d, want %q, %d", autoSave, data, code, want, wantCode)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package editor
+
+// Pick the dominant line ending for new rows and warn when the file mixes
+// \r\n and \n, which confuses diffs and many tools.
+func (ed *Editor) detectLineEndings() {
+	crlf := 0
+	for i := range ed.rows {
+		if ed.rows[i].crlf {
+			crlf++
+		}
+	}
+	lf := len(ed.rows) - crlf
+	ed.crlf = crlf > lf
+	if crlf > 0 && lf > 0 {
+		ed.setStatus("Mixed line endings: %d CRLF, %d LF (:fixeol to normalize)", crlf, lf)
+	}
+}
+
+// Give every row the same line ending: style "crlf" or "lf", or the
+// dominant one when style is "".
+func (ed *Editor) fixLineEndings(style string) {
+	if !ed.checkWritable() {
+		return
+	}
+	switch style {
+	case "":
+	case "crlf", "dos":
+		ed.crlf = true
+	case "lf", "unix":
+		ed.crlf = false
+	default:
+		ed.fail("Usage: fixeol [lf|crlf]")
+		return
+	}
+	changed := 0
+	for i := range ed.rows {
+		if ed.rows[i].crlf != ed.crlf {
+			ed.recordRows(i, 1, 1)
+			ed.rows[i].crlf = ed.crlf
+			changed++
+		}
+	}
+	if changed > 0 {
+		ed.dirty = true
+	}
+	name := "LF"
+	if ed.crlf {
+		name = "CRLF"
+	}
+	ed.setStatus("%d lines changed to %s", changed, name)
+}
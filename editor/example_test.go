@@ -0,0 +1,33 @@
+package editor_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"current/niso/editor"
+)
+
+// Drive the editor with keys as a terminal would send them: indent a new
+// file, break the line and save and quit with :wq.
+func ExampleRunScript() {
+	dir, err := ioutil.TempDir("", "exa")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "notes.txt")
+
+	keys := strings.NewReader("\t\r\t:wq\r")
+	ed, code, err := editor.RunScript(editor.DefaultConfig(), name, keys, ioutil.Discard)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	saved, _ := ioutil.ReadFile(name)
+	fmt.Printf("%q dirty=%v exit=%d\n", saved, ed.Dirty(), code)
+	// Output: "\t\n\t\t\n" dirty=false exit=0
+}
@@ -0,0 +1,81 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Write data to filename by way of a temporary file in the same directory
+// that is renamed over the target, so a failed write never leaves a
+// truncated file behind. A symlink target is followed to the file it points
+// to, unless followLinks is false in which case writing it is refused, and
+// anything that isn't a regular file is never overwritten.
+func writeFileAtomic(filename string, data []byte, followLinks bool) error {
+	target := filename
+	mode := newFileMode()
+	var orig os.FileInfo
+	if fi, err := os.Lstat(filename); err == nil {
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if !followLinks {
+				return fmt.Errorf("%s is a symlink", filename)
+			}
+			if target, err = filepath.EvalSymlinks(filename); err != nil {
+				return err
+			}
+			if fi, err = os.Stat(target); err != nil {
+				return err
+			}
+		}
+		if !fi.Mode().IsRegular() {
+			return fmt.Errorf("%s is not a regular file", filename)
+		}
+		// Keep the permissions, including setuid/setgid/sticky, and the
+		// owner of the file being replaced.
+		mode = fi.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+		orig = fi
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	dir, base := filepath.Split(target)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return err
+	}
+	// Remove the temporary file on any failure, a no-op after the rename.
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// Chown before chmod, changing the owner clears setuid bits.
+	if orig != nil {
+		copyOwner(tmp.Name(), orig)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
+
+// Bytes at the start of a file looked at to tell whether it is binary.
+const BINARY_SNIFF_BYTES = 8000
+
+// Whether data looks like the start of a binary file. Like git, take a NUL
+// byte as the sign, text files practically never contain one.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data, 0) >= 0
+}
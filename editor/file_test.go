@@ -0,0 +1,198 @@
+package editor
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveThroughSymlink(t *testing.T) {
+	target := writeTemp(t, "a.txt", "a\n")
+	link := filepath.Join(filepath.Dir(target), "link.txt")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip("no symlinks:", err)
+	}
+	keys := insertKey + "x\x1b:w\r"
+	ed, _ := runKeys(t, DefaultConfig(), link, keys)
+	if ed.Dirty() {
+		t.Errorf("not saved through the symlink: %s", ed.statusmsg)
+	}
+	if got, _ := ioutil.ReadFile(target); string(got) != "xa\n" {
+		t.Errorf("target holds %q, want the change", got)
+	}
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("%s is no longer a symlink", link)
+	}
+
+	cfg := DefaultConfig()
+	cfg.followSymlinks = false
+	ed, _ = runKeys(t, cfg, link, keys)
+	if !ed.Dirty() || !strings.Contains(ed.statusmsg, "is a symlink") {
+		t.Errorf("dirty %v with status %q, want saving refused", ed.Dirty(), ed.statusmsg)
+	}
+	if got, _ := ioutil.ReadFile(target); string(got) != "xa\n" {
+		t.Errorf("target holds %q, want it left alone", got)
+	}
+}
+
+func TestSaveRefusesDirectory(t *testing.T) {
+	dir := filepath.Dir(writeTemp(t, "a.txt", ""))
+	err := writeFileAtomic(dir, []byte("x\n"), true)
+	if err == nil || !strings.Contains(err.Error(), "not a regular file") {
+		t.Errorf("writing over a directory: %v, want it refused", err)
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		t.Errorf("%s is no longer a directory", dir)
+	}
+}
+
+func TestSaveReadOnlyFile(t *testing.T) {
+	path := writeTemp(t, "a.txt", "a\n")
+	if err := os.Chmod(path, 0444); err != nil {
+		t.Fatal(err)
+	}
+	// Opened read-only unless running as root, who may write anyway.
+	ed, _ := runKeys(t, DefaultConfig(), path, ":noreadonly\r"+insertKey+"x\x1b:w\r")
+	if ed.Dirty() {
+		t.Fatalf("not saved: %s", ed.statusmsg)
+	}
+	if got, _ := ioutil.ReadFile(path); string(got) != "xa\n" {
+		t.Errorf("file holds %q, want the change", got)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0444 {
+		t.Errorf("mode %v after saving, want 0444 kept", fi.Mode())
+	}
+}
+
+func TestSaveKeepsExecutableBit(t *testing.T) {
+	path := writeTemp(t, "run.sh", "#!/bin/sh\n")
+	if err := os.Chmod(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	ed, _ := runKeys(t, DefaultConfig(), path, "\x1b[F\r"+insertKey+"echo hi\x1b:w\r")
+	if ed.Dirty() {
+		t.Fatalf("not saved: %s", ed.statusmsg)
+	}
+	if got, _ := ioutil.ReadFile(path); string(got) != "#!/bin/sh\necho hi\n" {
+		t.Errorf("file holds %q, want the line added", got)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0755 {
+		t.Errorf("mode %v after saving, want 0755 kept", fi.Mode())
+	}
+}
+
+func TestOpenNonWritableFile(t *testing.T) {
+	path := writeTemp(t, "a.txt", "a\n")
+	if err := os.Chmod(path, 0444); err != nil {
+		t.Fatal(err)
+	}
+	if os.Getuid() == 0 {
+		// Root may write anything, so readOnlyFile can't say no.
+		t.Skip("running as root")
+	}
+	ed, _ := runKeys(t, DefaultConfig(), path, insertKey+"x")
+	if !ed.readOnly || ed.Dirty() || ed.Contents() != "a\n" {
+		t.Errorf("read-only %v, dirty %v with %q, want the edit refused", ed.readOnly, ed.Dirty(), ed.Contents())
+	}
+	if !strings.Contains(ed.statusmsg, ":noreadonly") {
+		t.Errorf("status %q, want the way to edit anyway", ed.statusmsg)
+	}
+	ed.refresh()
+	var frame bytes.Buffer
+	ed.drawStatusBar(&frame)
+	if !strings.Contains(frame.String(), "[RO]") {
+		t.Errorf("status bar %q, want [RO]", frame.String())
+	}
+
+	ed, _ = runKeys(t, DefaultConfig(), path, ":noreadonly\r"+insertKey+"x\x1b:w\r")
+	if ed.readOnly || ed.Dirty() {
+		t.Errorf("read-only %v, dirty %v after :noreadonly and saving, want neither: %s", ed.readOnly, ed.Dirty(), ed.statusmsg)
+	}
+	if got, _ := ioutil.ReadFile(path); string(got) != "xa\n" {
+		t.Errorf("file holds %q, want the change", got)
+	}
+}
+
+func TestReadOnlyBuffer(t *testing.T) {
+	// The same as for a file found not writable, as root too.
+	ed := New(DefaultConfig(), strings.NewReader(""), ioutil.Discard)
+	if err := ed.openArg(writeTemp(t, "a.txt", "a\n")); err != nil {
+		t.Fatal(err)
+	}
+	ed.readOnly = true
+	ed.typing = true
+	ed.typeChar('x')
+	ed.backspace()
+	ed.newline()
+	if ed.Dirty() || ed.Contents() != "a\n" {
+		t.Errorf("dirty %v with %q, want edits refused", ed.Dirty(), ed.Contents())
+	}
+	var bar bytes.Buffer
+	ed.drawStatusBar(&bar)
+	if !strings.Contains(bar.String(), "[RO]") {
+		t.Errorf("status bar %q, want [RO]", bar.String())
+	}
+	ed.execCommand("noreadonly")
+	ed.typeChar('x')
+	if ed.Contents() != "xa\n" {
+		t.Errorf("buffer %q after :noreadonly, want the edit made", ed.Contents())
+	}
+}
+
+func TestOpenBinaryFile(t *testing.T) {
+	blob := "\x7fELF\x02\x01\x00\x00\x1b[2J\xff\xfe\n\x00abc"
+	ed, out := frameEditor(t, 40, 5, "a.bin", blob)
+	ed.refresh()
+	frame := out.String()
+	// The control bytes, NUL among them, are drawn in caret notation.
+	if !strings.Contains(frame, "^B^A^@^@^[") || strings.ContainsAny(frame, "\x00\x02") || strings.Contains(frame, "\x1b[2J") {
+		t.Errorf("frame %q", frame)
+	}
+
+	// Opened to look at only: typing is refused, and saving leaves the
+	// file as it was.
+	path := writeTemp(t, "a.bin", blob)
+	ed, _ = runKeys(t, DefaultConfig(), path, insertKey+"x\x1b")
+	if !ed.binary || ed.Dirty() || ed.statusmsg != "Binary file, opened read-only" {
+		t.Errorf("binary %v, dirty %v, status %q", ed.binary, ed.Dirty(), ed.statusmsg)
+	}
+	runKeys(t, DefaultConfig(), path, ":w\r")
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != blob {
+		t.Errorf("file now %q, %v", data, err)
+	}
+}
+
+// The modified flag is set by a change, cleared by a save, and cleared again
+// by undo or redo back to the text saved.
+func TestDirtyAcrossSaves(t *testing.T) {
+	path := writeTemp(t, "a.txt", "text\n")
+	tests := []struct {
+		keys  string
+		dirty bool
+	}{
+		{"", false},
+		{insertKey + "x", true},
+		{insertKey + "x\x1b:w\r", false},
+		{insertKey + "x\x1b:w\r" + insertKey + "y", true},
+		{insertKey + "x\x1b:w\r" + insertKey + "y\x1bu", false},
+		{insertKey + "x\x1b:w\r" + insertKey + "y\x1buu", true},
+		{insertKey + "x\x1b:w\r" + insertKey + "y\x1buu\x12", false},
+		{insertKey + "x\x1b:w\r" + insertKey + "y\x1bu\x12", true},
+		// A change after undoing past the save can't get back to it.
+		{insertKey + "x\x1b:w\ru" + insertKey + "z\x1b\x12", true},
+		{insertKey + "x\x1b:w\r" + insertKey + "y\x1bu:w\ru", true},
+		{insertKey + "x\x1b:w\r" + insertKey + "y\x1bu:w\ru\x12", false},
+	}
+	for _, tt := range tests {
+		if err := ioutil.WriteFile(path, []byte("text\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		ed, _ := runKeys(t, DefaultConfig(), path, tt.keys)
+		if ed.Dirty() != tt.dirty {
+			t.Errorf("%q: dirty %v, want %v", tt.keys, ed.Dirty(), tt.dirty)
+		}
+	}
+}
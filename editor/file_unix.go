@@ -0,0 +1,35 @@
+//go:build !windows
+// +build !windows
+
+package editor
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// Give path the owner and group of the file described by fi. Only root can
+// give a file away, so failing is expected and ignored.
+func copyOwner(path string, fi os.FileInfo) {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		os.Chown(path, int(st.Uid), int(st.Gid))
+	}
+}
+
+// Permissions for a newly created file: 0666 less the process umask, the
+// same as any other program creating it.
+func newFileMode() os.FileMode {
+	// The umask can only be read by setting it, so put it straight back.
+	mask := syscall.Umask(0)
+	syscall.Umask(mask)
+	return 0666 &^ os.FileMode(mask)
+}
+
+// Whether the user may not write to path, e.g. a file owned by someone else
+// or on a read-only mount. A file that doesn't exist yet isn't.
+func readOnlyFile(path string) bool {
+	err := unix.Access(path, unix.W_OK)
+	return err == unix.EACCES || err == unix.EPERM || err == unix.EROFS
+}
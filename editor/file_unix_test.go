@@ -0,0 +1,46 @@
+//go:build !windows
+// +build !windows
+
+package editor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestSaveRefusesNamedPipe(t *testing.T) {
+	path := filepath.Join(filepath.Dir(writeTemp(t, "a.txt", "")), "fifo")
+	if err := syscall.Mkfifo(path, 0644); err != nil {
+		t.Skip("no named pipes:", err)
+	}
+	err := writeFileAtomic(path, []byte("x\n"), true)
+	if err == nil || !strings.Contains(err.Error(), "not a regular file") {
+		t.Errorf("writing over a named pipe: %v, want it refused", err)
+	}
+	if fi, err := os.Lstat(path); err != nil || fi.Mode()&os.ModeNamedPipe == 0 {
+		t.Errorf("%s is no longer a named pipe", path)
+	}
+}
+
+func TestNewFileHonorsUmask(t *testing.T) {
+	old := syscall.Umask(027)
+	defer syscall.Umask(old)
+	path := filepath.Join(filepath.Dir(writeTemp(t, "a.txt", "")), "new.txt")
+	if err := writeFileAtomic(path, []byte("x\n"), true); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0640 {
+		t.Errorf("mode %v for a new file, want 0640 with umask 027", fi.Mode())
+	}
+}
+
+func TestOpenDeviceArgument(t *testing.T) {
+	_, _, err := RunScript(DefaultConfig(), os.DevNull, strings.NewReader(""), ioutil.Discard)
+	if err == nil || err.Error() != os.DevNull+" is not a regular file or a directory" {
+		t.Errorf("opening %s: %v", os.DevNull, err)
+	}
+}
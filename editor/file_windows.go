@@ -0,0 +1,20 @@
+//go:build windows
+// +build windows
+
+package editor
+
+import "os"
+
+// Files have no Unix owner on Windows.
+func copyOwner(path string, fi os.FileInfo) {}
+
+func newFileMode() os.FileMode {
+	return 0666
+}
+
+// Whether path has the read-only attribute, which Go reports as a missing
+// write permission.
+func readOnlyFile(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.Mode().Perm()&0200 == 0
+}
@@ -0,0 +1,139 @@
+package editor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Returned by operations given up on with Escape.
+var errCancelled = errors.New("cancelled")
+
+// How often a running filter checks whether it was cancelled.
+const FILTER_POLL = 50 * time.Millisecond
+
+// Run a shell command with input on stdin and return its stdout. On failure
+// the error carries the command's stderr, which says more than its exit
+// status. The command is killed when cancelled reports true.
+func runFilter(command, input string, cancelled func() bool) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := shellCommand(command)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("%s: %v", command, err)
+	}
+	err := waitCommand(cmd, cancelled)
+	if err == errCancelled {
+		return "", err
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%s: %s", command, strings.Join(strings.Fields(msg), " "))
+		}
+		return "", fmt.Errorf("%s: %v", command, err)
+	}
+	return stdout.String(), nil
+}
+
+// Wait for a started command to exit, killing it and returning errCancelled
+// if cancelled reports true first.
+func waitCommand(cmd *exec.Cmd, cancelled func() bool) error {
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-time.After(FILTER_POLL):
+			if cancelled() {
+				killCommand(cmd)
+				<-done
+				return errCancelled
+			}
+		}
+	}
+}
+
+// Run a shell command and return its stdout and stderr as they were
+// interleaved, whether or not it fails.
+func runCommand(command string, cancelled func() bool) (string, error) {
+	var out bytes.Buffer
+	cmd := shellCommand(command)
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("%s: %v", command, err)
+	}
+	err := waitCommand(cmd, cancelled)
+	if err == errCancelled {
+		return "", err
+	}
+	return out.String(), err
+}
+
+// Replace the buffer with text, keeping the cursor on the same line and
+// column as far as they still exist. Return false if nothing changed.
+func (ed *Editor) replaceContents(text string) bool {
+	if text == ed.Contents() {
+		return false
+	}
+	old := linesOf(ed.rows)
+	ed.rows = nil
+	ed.noEOL = false
+	// Reading from memory can't fail.
+	ed.readRows(strings.NewReader(text))
+	ed.recordChange(0, old, len(ed.rows))
+	ed.dirty = true
+	ed.clampCursor()
+	return true
+}
+
+// Feed the buffer to a shell command and replace it with the output, e.g.
+// "sort" or "gofmt". The buffer is left alone when the command fails.
+func (ed *Editor) filterBuffer(command string) {
+	if !ed.checkWritable() {
+		return
+	}
+	out, err := runFilter(command, ed.Contents(), ed.keys.cancelled)
+	if err == errCancelled {
+		ed.setStatus("Cancelled")
+		return
+	}
+	if err != nil {
+		ed.fail("%v", err)
+		return
+	}
+	if !ed.replaceContents(out) {
+		ed.setStatus("%s: no changes", command)
+		return
+	}
+	ed.setStatus("Filtered through %s, %d lines", command, len(ed.rows))
+}
+
+// Prompt for a command to filter the buffer through.
+func (ed *Editor) filterPrompt() {
+	line, ok := ed.prompt("Filter through: ", ed.cmdHistory)
+	if line = strings.TrimSpace(line); ok && line != "" {
+		ed.filterBuffer(line)
+	}
+}
+
+// Run the buffer through the formatter configured for its filetype, if any.
+func (ed *Editor) format() error {
+	if ed.syntax == nil {
+		return nil
+	}
+	command := ed.cfg.formatters[ed.syntax.filetype]
+	if command == "" {
+		return nil
+	}
+	out, err := runFilter(command, ed.Contents(), ed.keys.cancelled)
+	if err != nil {
+		return err
+	}
+	ed.replaceContents(out)
+	return nil
+}
@@ -0,0 +1,109 @@
+package editor
+
+// Folds hide the indented block under a row, the header, which is drawn as a
+// single line with a marker. Only the header is flagged, the extent of the
+// block is worked out from the indentation whenever it is needed, so folds
+// follow edits without bookkeeping.
+
+// Whether row holds only whitespace. Blank rows belong to whatever block
+// surrounds them.
+func (row *Row) blank() bool {
+	return firstNonBlank(row.chars) == len(row.chars)
+}
+
+// Width of the row's leading whitespace on screen.
+func (row *Row) indent() int {
+	return firstNonBlank(row.render)
+}
+
+// Last row of the block under row y: the rows after it that are blank or
+// indented deeper, not counting blank rows at the end. y if there are none.
+func (ed *Editor) blockEnd(y int) int {
+	indent, end := ed.rows[y].indent(), y
+	for i := y + 1; i < len(ed.rows); i++ {
+		if ed.rows[i].blank() {
+			continue
+		}
+		if ed.rows[i].indent() <= indent {
+			break
+		}
+		end = i
+	}
+	return end
+}
+
+// Last row hidden by a fold at row y, y itself when it is not folded.
+func (ed *Editor) foldEnd(y int) int {
+	if y >= len(ed.rows) || !ed.rows[y].folded {
+		return y
+	}
+	return ed.blockEnd(y)
+}
+
+// The row shown in place of row y: the header of the outermost fold hiding
+// it, or y when it is visible.
+func (ed *Editor) foldHeader(y int) int {
+	if !ed.hasFolds || y >= len(ed.rows) {
+		return y
+	}
+	header := y
+	// A header is indented less than every row between it and y, so only
+	// rows that lower the indentation need a look. Nothing encloses a row
+	// at column 0.
+	min := -1
+	if !ed.rows[y].blank() {
+		min = ed.rows[y].indent()
+	}
+	for i := y - 1; i >= 0 && min != 0; i-- {
+		row := &ed.rows[i]
+		if row.blank() {
+			continue
+		}
+		if indent := row.indent(); min < 0 || indent < min {
+			min = indent
+			if row.folded && ed.blockEnd(i) >= y {
+				header = i
+			}
+		}
+	}
+	return header
+}
+
+// Number of screen lines the rows from..to-1 take, counting at most limit.
+func (ed *Editor) visibleLines(from, to, limit int) int {
+	if !ed.hasFolds {
+		if to-from > limit {
+			return limit
+		}
+		return to - from
+	}
+	n := 0
+	for y := from; y < to && n < limit; y = ed.foldEnd(y) + 1 {
+		n++
+	}
+	return n
+}
+
+// Fold the block under the cursor row.
+func (ed *Editor) fold() {
+	if ed.lazy != nil {
+		ed.fail("Can't fold in a file opened read-only")
+		return
+	}
+	if ed.numRows() == 0 || ed.blockEnd(ed.cy) == ed.cy {
+		ed.setStatus("Nothing to fold")
+		return
+	}
+	ed.rows[ed.cy].folded = true
+	ed.hasFolds = true
+	ed.cx = 0
+}
+
+// Open the fold under the cursor.
+func (ed *Editor) unfold() {
+	if ed.numRows() == 0 || ed.lazy != nil || !ed.rows[ed.cy].folded {
+		ed.setStatus("No fold here")
+		return
+	}
+	ed.rows[ed.cy].folded = false
+}
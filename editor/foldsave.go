@@ -0,0 +1,145 @@
+package editor
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Files whose folds are remembered, the least recently left ones are
+// forgotten first.
+const FOLDS_MAX = 500
+
+// The folded rows of a file, and a hash of the text they were folded in.
+type savedFolds struct {
+	path string
+	hash uint64
+	rows []int
+}
+
+// Location of the fold cache, next to the config file.
+func foldsPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "exa", "folds")
+}
+
+// Read the fold cache, oldest entry first. The file holds one
+// "hash row,row,... path" entry per line, the hash in hex; lines that don't
+// parse are skipped.
+func loadFolds(path string) []savedFolds {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var entries []savedFolds
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.SplitN(s.Text(), " ", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		e := savedFolds{path: fields[2]}
+		if e.hash, err = strconv.ParseUint(fields[0], 16, 64); err != nil {
+			continue
+		}
+		for _, field := range strings.Split(fields[1], ",") {
+			y, err := strconv.Atoi(field)
+			if err != nil {
+				e.rows = nil
+				break
+			}
+			e.rows = append(e.rows, y)
+		}
+		if e.rows != nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Hash of the text of the buffer, to tell whether folds saved for it still
+// fit. Line endings don't count.
+func (ed *Editor) textHash() uint64 {
+	h := fnv.New64a()
+	for i := range ed.rows {
+		h.Write([]byte(ed.rows[i].chars))
+		h.Write([]byte{'\n'})
+	}
+	return h.Sum64()
+}
+
+// Remember which rows of the file are folded, for the next time it's
+// opened with the same text. With savefolds off this does nothing, and
+// failing to is not worth a message.
+func (ed *Editor) saveFolds() {
+	cache := foldsPath()
+	key := ed.positionKey()
+	if !ed.cfg.saveFolds || cache == "" || key == "" || ed.lazy != nil || ed.partial {
+		return
+	}
+	var rows []string
+	for y := 0; y < len(ed.rows) && ed.hasFolds; y++ {
+		if ed.rows[y].folded {
+			rows = append(rows, strconv.Itoa(y))
+		}
+	}
+	entries := loadFolds(cache)
+	if len(entries) >= FOLDS_MAX {
+		entries = entries[len(entries)-FOLDS_MAX+1:]
+	}
+	var b strings.Builder
+	found := false
+	for _, e := range entries {
+		if e.path == key {
+			found = true
+			continue
+		}
+		ys := make([]string, len(e.rows))
+		for i, y := range e.rows {
+			ys[i] = strconv.Itoa(y)
+		}
+		fmt.Fprintf(&b, "%x %s %s\n", e.hash, strings.Join(ys, ","), e.path)
+	}
+	if len(rows) == 0 && !found {
+		// Nothing to remember or to forget.
+		return
+	}
+	if len(rows) > 0 {
+		fmt.Fprintf(&b, "%x %s %s\n", ed.textHash(), strings.Join(rows, ","), key)
+	}
+	if os.MkdirAll(filepath.Dir(cache), 0700) == nil {
+		writeFileAtomic(cache, []byte(b.String()), true)
+	}
+}
+
+// Fold again the rows that were folded when the file was last left, if its
+// text is the same as then. Folds saved for other text are ignored, and
+// replaced the next time the file's folds are saved.
+func (ed *Editor) restoreFolds() {
+	cache := foldsPath()
+	key := ed.positionKey()
+	if !ed.cfg.saveFolds || cache == "" || key == "" || ed.lazy != nil || ed.partial {
+		return
+	}
+	for _, e := range loadFolds(cache) {
+		if e.path != key || e.hash != ed.textHash() {
+			continue
+		}
+		for _, y := range e.rows {
+			if y >= 0 && y < len(ed.rows) && ed.blockEnd(y) > y {
+				ed.rows[y].folded = true
+				ed.hasFolds = true
+			}
+		}
+		return
+	}
+}
@@ -0,0 +1,98 @@
+package editor
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+// How often a followed file is looked at for new lines.
+const FOLLOW_INTERVAL = time.Second
+
+// Start or stop following the file as it grows, like tail -f. While
+// following the buffer is read-only.
+func (ed *Editor) toggleFollow() {
+	if ed.follow != nil {
+		ed.stopFollow()
+		ed.setStatus("Stopped following %s", ed.displayName())
+		return
+	}
+	if ed.filename == "" || ed.diskSize < 0 {
+		ed.setStatus("No file to follow")
+		return
+	}
+	if ed.dirty {
+		ed.fail("Can't follow with unsaved changes")
+		return
+	}
+	ed.follow = time.NewTicker(FOLLOW_INTERVAL)
+	ed.keys.tick = ed.follow.C
+	ed.cy, ed.cx = ed.numRows()-1, 0
+	ed.clampCursor()
+	ed.setStatus("Following %s, leave the last line to pause", ed.displayName())
+}
+
+func (ed *Editor) stopFollow() {
+	if ed.follow != nil {
+		ed.follow.Stop()
+		ed.follow, ed.keys.tick = nil, nil
+	}
+}
+
+// Add what was appended to the file since it was last read. The view stays
+// on the last line if the cursor was there, moving off it pauses following
+// until the cursor is back. A file that shrank, e.g. a rotated log, is read
+// again from the start.
+func (ed *Editor) followFile() {
+	if !ed.changedOnDisk() {
+		return
+	}
+	pinned := ed.cy >= ed.numRows()-1
+	fi, err := os.Stat(ed.filename)
+	if err != nil || fi.Size() < ed.diskSize || !ed.readAppended() {
+		ed.reload()
+	}
+	ed.recordDiskState()
+	if pinned {
+		ed.cy, ed.cx = ed.numRows()-1, 0
+		ed.clampCursor()
+	}
+}
+
+// Read the bytes appended to the file into the buffer. Return false if that
+// failed and the file has to be read again whole.
+func (ed *Editor) readAppended() bool {
+	if ed.lazy != nil {
+		return ed.lazy.index(func(int64) bool { return true }) == nil
+	}
+	f, err := os.Open(ed.filename)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	if _, err := f.Seek(ed.diskSize, 0); err != nil {
+		return false
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return false
+	}
+	text := string(data)
+	from := len(ed.rows)
+	// Rows changed without undo knowing, and following needs the buffer
+	// unmodified anyway.
+	ed.clearUndo()
+	// An unfinished last line goes on with the appended text.
+	if ed.noEOL && from > 0 {
+		from--
+		text = ed.rows[from].chars + text
+		ed.rows = ed.rows[:from]
+	}
+	// Reading from memory can't fail.
+	ed.readRows(strings.NewReader(text))
+	if from > 0 {
+		ed.updateSyntax(from - 1)
+	}
+	return true
+}
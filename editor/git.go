@@ -0,0 +1,65 @@
+package editor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Return the checked out branch of the Git repository containing filename,
+// or the short commit hash of a detached HEAD. Found by reading .git/HEAD in
+// the nearest parent directory that has one. "" when not in a repository.
+func gitBranch(filename string) string {
+	dir, err := filepath.Abs(filepath.Dir(filename))
+	if err != nil {
+		return ""
+	}
+	for {
+		if gitdir := findGitDir(dir); gitdir != "" {
+			head, err := ioutil.ReadFile(filepath.Join(gitdir, "HEAD"))
+			if err != nil {
+				return ""
+			}
+			ref := strings.TrimSpace(string(head))
+			if strings.HasPrefix(ref, "ref: ") {
+				return strings.TrimPrefix(strings.TrimPrefix(ref, "ref: "), "refs/heads/")
+			}
+			if len(ref) > 7 {
+				ref = ref[:7]
+			}
+			return ref
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Return the Git directory of a work tree rooted at dir, if any. A .git file
+// instead of a directory, as used by worktrees and submodules, points to it.
+func findGitDir(dir string) string {
+	path := filepath.Join(dir, ".git")
+	fi, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	if fi.IsDir() {
+		return path
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	line := strings.TrimSpace(string(b))
+	if !strings.HasPrefix(line, "gitdir: ") {
+		return ""
+	}
+	gitdir := strings.TrimPrefix(line, "gitdir: ")
+	if !filepath.IsAbs(gitdir) {
+		gitdir = filepath.Join(dir, gitdir)
+	}
+	return gitdir
+}
@@ -0,0 +1,72 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Whether c can be part of a file name written in text.
+func isPathChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("/._-~+@%:\\", c) >= 0
+}
+
+// The path-like word around index i of s, "" if there is none.
+func pathAt(s string, i int) string {
+	start, end := i, i
+	for start > 0 && isPathChar(s[start-1]) {
+		start--
+	}
+	for end < len(s) && isPathChar(s[end]) {
+		end++
+	}
+	// Punctuation ending a sentence, as in "see main.go.", isn't part of it.
+	return strings.TrimRight(s[start:end], ".:")
+}
+
+// Find name relative to the current file's directory, the working directory
+// and then the directories of the path setting. Return "" if it isn't in
+// any of them.
+func (ed *Editor) findFile(name string) string {
+	if strings.HasPrefix(name, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			name = filepath.Join(home, name[2:])
+		}
+	}
+	if filepath.IsAbs(name) {
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+		return ""
+	}
+	dirs := []string{filepath.Dir(ed.filename), "."}
+	dirs = append(dirs, ed.cfg.path...)
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Open the file named under the cursor, like gf in vim. A ":line" or
+// ":line:col" suffix jumps there.
+func (ed *Editor) gotoFile() {
+	if ed.numRows() == 0 {
+		return
+	}
+	token := pathAt(ed.row(ed.cy).chars, ed.cx)
+	if token == "" {
+		ed.setStatus("No file name under the cursor")
+		return
+	}
+	name, line, col := parseFileArg(token)
+	path := ed.findFile(name)
+	if path == "" {
+		ed.fail("Can't find file %q", name)
+		return
+	}
+	ed.edit(path, line, col)
+}
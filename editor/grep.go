@@ -0,0 +1,122 @@
+package editor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Most matches collected by one search, the rest are dropped.
+const GREP_MAX = 1000
+
+// Stops the walk once GREP_MAX matches are in.
+var errGrepFull = errors.New("too many matches")
+
+// Search the files under the working directory for a regular expression
+// and return the matching lines as "file:line: text". Binary and large
+// files are skipped, and unless all is set so are hidden directories and
+// whatever .gitignore files and the ignore setting exclude. .git never is
+// searched.
+func (ed *Editor) grep(re *regexp.Regexp, all bool) ([]string, error) {
+	var matches []string
+	ig := &ignorer{}
+	ig.add(ed.cfg.ignore)
+	err := filepath.Walk(".", func(path string, info os.FileInfo, err error) error {
+		if ed.keys.cancelled() {
+			return errCancelled
+		}
+		if err != nil {
+			// Unreadable entries are left out rather than ending the search.
+			return nil
+		}
+		name := info.Name()
+		if info.IsDir() && name == ".git" {
+			return filepath.SkipDir
+		}
+		if !all && path != "." && (ig.ignored(path, info.IsDir()) || info.IsDir() && strings.HasPrefix(name, ".")) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			if !all {
+				ig.load(path)
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() || info.Size() > ed.cfg.largeFile {
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		sniff := data
+		if len(sniff) > BINARY_SNIFF_BYTES {
+			sniff = sniff[:BINARY_SNIFF_BYTES]
+		}
+		if isBinary(sniff) {
+			return nil
+		}
+		for n, line := range bytes.Split(data, []byte("\n")) {
+			if !re.Match(line) {
+				continue
+			}
+			line = bytes.TrimSuffix(line, []byte("\r"))
+			matches = append(matches, fmt.Sprintf("%s:%d: %s", path, n+1, line))
+			if len(matches) == GREP_MAX {
+				return errGrepFull
+			}
+		}
+		return nil
+	})
+	if err == errGrepFull {
+		err = nil
+	}
+	return matches, err
+}
+
+// Search the project for pattern and list the matches in the output pane,
+// where Enter jumps to one. all includes ignored files.
+func (ed *Editor) grepToPane(pattern string, all bool) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		ed.fail("Bad pattern: %v", err)
+		return
+	}
+	ed.setStatus("Searching for %s...", pattern)
+	ed.refresh()
+	matches, err := ed.grep(re, all)
+	if err == errCancelled {
+		ed.setStatus("Cancelled")
+		return
+	}
+	if err != nil {
+		ed.fail("grep: %v", err)
+		return
+	}
+	if len(matches) == 0 {
+		ed.setStatus("No matches for %s", pattern)
+		return
+	}
+	title := "grep " + pattern
+	if len(matches) == GREP_MAX {
+		title += fmt.Sprintf(" (first %d matches)", GREP_MAX)
+	}
+	ed.setStatus("")
+	ed.showPane(title, matches)
+}
+
+// Prompt for a pattern to search the project for.
+func (ed *Editor) grepPrompt() {
+	pattern, ok := ed.prompt("Grep: ", nil)
+	if ok && pattern != "" {
+		ed.grepToPane(pattern, false)
+	}
+}
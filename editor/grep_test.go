@@ -0,0 +1,39 @@
+package editor
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGrepMatchesDrawnEscaped(t *testing.T) {
+	dir := filepath.Dir(writeTemp(t, "notes.txt", "plain line\nneedle\x1b[2J\there\n"))
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	ed := New(DefaultConfig(), strings.NewReader(""), ioutil.Discard)
+	ed.grepToPane("needle", false)
+	if ed.pane == nil || len(ed.pane.lines) != 1 {
+		t.Fatalf("pane %+v, want one match", ed.pane)
+	}
+	if want := "notes.txt:2: needle\x1b[2J\there"; ed.pane.lines[0] != want {
+		t.Errorf("match %q, want %q", ed.pane.lines[0], want)
+	}
+	// Keep the cursor off the match to see it drawn plain.
+	ed.pane.cursor = -1
+	var b bytes.Buffer
+	ed.drawPaneRow(&b, 1)
+	want := "notes.txt:2: needle\x1b[7m^[\x1b[m[2J        here\x1b[K"
+	if got := b.String(); got != want {
+		t.Errorf("drawn %q, want %q", got, want)
+	}
+}
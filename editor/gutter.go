@@ -0,0 +1,71 @@
+package editor
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// Digits always given to line numbers, so most files don't shift their
+// text over as they grow past 9 or 99 lines.
+const NUMBER_MIN_DIGITS = 3
+
+// Columns drawn before the text of each row: diagnostic signs when some row
+// has one, then line numbers and their separator when shown.
+func (ed *Editor) gutterWidth() int {
+	width := 0
+	if ed.hasDiags {
+		width += DIAG_GUTTER
+	}
+	if ed.cfg.number {
+		width += ed.numberWidth() + len(ed.cfg.numberSep)
+	}
+	return width
+}
+
+// Columns of the line numbers, wide enough for the last row's.
+func (ed *Editor) numberWidth() int {
+	digits := len(strconv.Itoa(ed.numRows()))
+	if digits < NUMBER_MIN_DIGITS {
+		digits = NUMBER_MIN_DIGITS
+	}
+	return digits
+}
+
+// Draw the gutter of row filerow: a W for a warning and an E for any other
+// diagnostic, and the line number. On a screen too narrow for all of it,
+// what fits of the start.
+func (ed *Editor) drawGutter(ab *bytes.Buffer, row *Row, filerow int) {
+	left := ed.gutter
+	if left == 0 {
+		return
+	}
+	if ed.hasDiags {
+		sign := " "
+		if row.diag != "" {
+			sign = "E"
+			if strings.HasPrefix(strings.ToLower(row.diag), "warning") {
+				sign = "W"
+			}
+			sign = "\x1b[" + ed.hlColor(HL_DIAG_SIGN) + "m" + sign + "\x1b[m"
+		}
+		ab.WriteString(sign)
+		ab.WriteString(strings.Repeat(" ", clamp(DIAG_GUTTER, 1, left)-1))
+		left -= clamp(DIAG_GUTTER, 0, left)
+	}
+	if !ed.cfg.number || left == 0 {
+		return
+	}
+	num := strconv.Itoa(filerow + 1)
+	pad := strings.Repeat(" ", ed.numberWidth()-len(num))
+	if ed.cfg.numberAlign == "left" {
+		num += pad
+	} else {
+		num = pad + num
+	}
+	num = (num + ed.cfg.numberSep)[:clamp(len(num)+len(ed.cfg.numberSep), 0, left)]
+	if color := ed.hlColor(HL_NUMBER); color != "" {
+		num = "\x1b[" + color + "m" + num + "\x1b[m"
+	}
+	ab.WriteString(num)
+}
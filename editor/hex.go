@@ -0,0 +1,203 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Bytes shown on each line of the hex view.
+const HEX_ROW_BYTES = 16
+
+// A read-only hex dump of a file, shown in place of the buffer. It reads
+// the file on disk, never the buffer, and only the lines on screen.
+type hexView struct {
+	f    *os.File
+	size int64
+	// Offset of the byte under the cursor and of the first byte on screen.
+	cursor, top int64
+}
+
+// Toggle the hex view of the current file.
+func (ed *Editor) toggleHex() {
+	if ed.hex != nil {
+		ed.closeHex()
+		return
+	}
+	if ed.filename == "" {
+		ed.fail("No file name")
+		return
+	}
+	f, err := os.Open(ed.filename)
+	if err != nil {
+		ed.fail("Can't open %s: %v", ed.displayName(), err)
+		return
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		ed.fail("Can't open %s: %v", ed.displayName(), err)
+		return
+	}
+	ed.hex = &hexView{f: f, size: fi.Size()}
+	if ed.dirty {
+		ed.setStatus("Hex view shows the file on disk, not unsaved changes")
+	}
+}
+
+func (ed *Editor) closeHex() {
+	ed.hex.f.Close()
+	ed.hex = nil
+}
+
+// Handle a key in the hex view. Return false to quit.
+func (ed *Editor) hexKeyPress(ch EdKey) bool {
+	hv := ed.hex
+	page := int64(ed.screenrows * HEX_ROW_BYTES)
+	switch ch {
+	case ARW_LEFT:
+		hv.cursor--
+	case ARW_RIGHT:
+		hv.cursor++
+	case ARW_UP:
+		hv.cursor -= HEX_ROW_BYTES
+	case ARW_DOWN:
+		hv.cursor += HEX_ROW_BYTES
+	case PG_UP:
+		hv.cursor -= page
+		hv.top -= page
+	case PG_DOWN:
+		hv.cursor += page
+		hv.top += page
+	case HOME_KEY:
+		hv.cursor -= hv.cursor % HEX_ROW_BYTES
+	case END_KEY:
+		hv.cursor += HEX_ROW_BYTES - 1 - hv.cursor%HEX_ROW_BYTES
+	case ':':
+		return ed.commandMode()
+	case 'q', 0x1b:
+		ed.closeHex()
+	default:
+		if ed.cfg.keymap[ch] == "quit" {
+			return ed.quit()
+		}
+	}
+	return true
+}
+
+// Move the hex cursor to an offset typed as decimal, or hex with 0x.
+func (ed *Editor) hexGoto(arg string) {
+	off, err := strconv.ParseInt(arg, 0, 64)
+	if err != nil || off < 0 {
+		ed.fail("Bad offset %q", arg)
+		return
+	}
+	ed.hex.cursor = off
+	ed.hex.top = off - off%HEX_ROW_BYTES - int64(ed.screenrows/2*HEX_ROW_BYTES)
+}
+
+// Keep the cursor inside the file and on screen.
+func (ed *Editor) hexScroll() {
+	hv := ed.hex
+	if hv.cursor >= hv.size {
+		hv.cursor = hv.size - 1
+	}
+	if hv.cursor < 0 {
+		hv.cursor = 0
+	}
+	page := int64(ed.screenrows * HEX_ROW_BYTES)
+	// The last line that holds any bytes.
+	last := (hv.size - 1) / HEX_ROW_BYTES * HEX_ROW_BYTES
+	if hv.top > last-page+HEX_ROW_BYTES {
+		hv.top = last - page + HEX_ROW_BYTES
+	}
+	if row := hv.cursor - hv.cursor%HEX_ROW_BYTES; row < hv.top {
+		hv.top = row
+	} else if row >= hv.top+page {
+		hv.top = row - page + HEX_ROW_BYTES
+	}
+	if hv.top < 0 {
+		hv.top = 0
+	}
+}
+
+// Draw screen line y of the hex view like xxd: offset, bytes in groups of
+// two, then the printable ones as text.
+func (ed *Editor) drawHexRow(ab *bytes.Buffer, y int) {
+	hv := ed.hex
+	off := hv.top + int64(y*HEX_ROW_BYTES)
+	if off >= hv.size {
+		ab.WriteString("~\x1b[K")
+		return
+	}
+	buf := make([]byte, HEX_ROW_BYTES)
+	// A failed read leaves the line short rather than stopping the view.
+	n, _ := hv.f.ReadAt(buf, off)
+	var line bytes.Buffer
+	fmt.Fprintf(&line, "%08x: ", off)
+	for i := 0; i < HEX_ROW_BYTES; i++ {
+		switch {
+		case i >= n:
+			line.WriteString("  ")
+		case off+int64(i) == hv.cursor:
+			fmt.Fprintf(&line, "\x1b[7m%02x\x1b[m", buf[i])
+		default:
+			fmt.Fprintf(&line, "%02x", buf[i])
+		}
+		if i%2 == 1 {
+			line.WriteByte(' ')
+		}
+	}
+	line.WriteByte(' ')
+	for i := 0; i < n; i++ {
+		c := buf[i]
+		if c < ' ' || c > '~' {
+			c = '.'
+		}
+		if off+int64(i) == hv.cursor {
+			line.WriteString("\x1b[7m" + string(c) + "\x1b[m")
+		} else {
+			line.WriteByte(c)
+		}
+	}
+	ab.Write(truncateEscaped(line.Bytes(), ed.width))
+	ab.WriteString("\x1b[K")
+}
+
+// Cut text holding SGR sequences to width visible characters, keeping the
+// sequences intact.
+func truncateEscaped(text []byte, width int) []byte {
+	visible := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] == 0x1b {
+			for i < len(text) && text[i] != 'm' {
+				i++
+			}
+			continue
+		}
+		if visible == width {
+			// Reset in case the cut came inside a highlight.
+			return append(text[:i:i], "\x1b[m"...)
+		}
+		visible++
+	}
+	return text
+}
+
+// Screen position of the hex cursor, 0 based, on its hex digits.
+func (ed *Editor) hexCursor() (y, x int) {
+	hv := ed.hex
+	i := int(hv.cursor % HEX_ROW_BYTES)
+	// "00000000: " then 5 columns per pair of bytes.
+	return int(hv.cursor-hv.top) / HEX_ROW_BYTES, 10 + i*2 + i/2
+}
+
+// Left and right parts of the status bar in the hex view.
+func (ed *Editor) hexStatus() (left, right string) {
+	name := ed.statusName()
+	hv := ed.hex
+	left = fmt.Sprintf("%s [hex] - %d bytes", name, hv.size)
+	right = fmt.Sprintf("0x%x/0x%x", hv.cursor, hv.size)
+	return left, right
+}
@@ -0,0 +1,280 @@
+package editor
+
+import (
+	"strings"
+)
+
+// Highlight class of each rendered character, see Row.hl.
+const (
+	HL_NORMAL uint8 = iota
+	HL_CONFLICT_MARKER
+	HL_OURS
+	HL_BASE
+	HL_THEIRS
+	HL_SPELL
+	HL_CONTROL
+	// Not in hl, the marker drawn after a fold header, the color columns
+	// and the markers of text cut off at the screen edges.
+	HL_FOLD
+	HL_COLORCOLUMN
+	HL_EXTENDS
+	// Text past maxwidth and trailing whitespace, when marked.
+	HL_LONG
+	HL_TRAILING
+	HL_TODO
+	// Comment text, marked like TODO markers on the rows around the screen.
+	HL_COMMENT
+	// Leading whitespace not in the buffer's indentation style.
+	HL_BAD_INDENT
+	// Not in hl, the cursor row with the crosshair on.
+	HL_CROSSHAIR
+	// A match of the last search, with hlsearch on.
+	HL_MATCH
+	// Not in hl, the gutter sign and shading of a row with a diagnostic.
+	HL_DIAG_SIGN
+	HL_DIAG_LINE
+	// Not in hl, a block keyword and its partner, with matchpairs on.
+	HL_PAIR
+	// Not in hl, a cursor besides the main one.
+	HL_CURSOR
+	// Not in hl, the minimap and the part of it for the lines on screen.
+	HL_MINIMAP
+	HL_MINIMAP_VIEW
+	// Not in hl, line numbers in the gutter.
+	HL_NUMBER
+	// Not in hl, lines of a diff in the output pane.
+	HL_DIFF_ADD
+	HL_DIFF_DEL
+	HL_DIFF_HUNK
+)
+
+// State the highlighter carries from the end of one row into the next, for
+// constructs spanning lines.
+const (
+	ST_NONE uint8 = iota
+	ST_OURS
+	ST_BASE
+	ST_THEIRS
+)
+
+// Set in the state on top of the above inside a block comment, e.g. /* */.
+const ST_COMMENT uint8 = 0x80
+
+// Highlight the row given the state the previous row ended in, and with the
+// comments of syntax. Return the state it leaves for the next row.
+func (row *Row) highlight(state uint8, syntax *Syntax) uint8 {
+	class, next := conflictLine(row.chars, state&^ST_COMMENT)
+	if syntax.eachComment(row.chars, state&ST_COMMENT != 0, func(from, to int) {}) {
+		next |= ST_COMMENT
+	}
+	if cap(row.hl) >= len(row.render) {
+		row.hl = row.hl[:len(row.render)]
+	} else {
+		row.hl = make([]uint8, len(row.render))
+	}
+	for i := range row.hl {
+		row.hl[i] = class
+	}
+	for _, rx := range row.ctrl {
+		row.hl[rx], row.hl[rx+1] = HL_CONTROL, HL_CONTROL
+	}
+	row.hlStart, row.hlState = state, next
+	row.marked = false
+	return next
+}
+
+// Rows above and below the screen marked ahead of scrolling.
+const MARK_MARGIN = 10
+
+// Add the marks that are only worth working out for rows on screen,
+// comments, TODO markers, misspelled words, bad indentation and search
+// matches, to the rows around the screen that haven't had them since they
+// were last highlighted. Doing the whole buffer would be wasted work on
+// large files.
+func (ed *Editor) markVisible() {
+	spell := ed.spellCheckable() && ed.loadSpellDict()
+	matches := ed.showMatches()
+	from := ed.rowoff - MARK_MARGIN
+	if from < 0 {
+		from = 0
+	}
+	to := ed.rowoff + ed.screenrows + MARK_MARGIN
+	if to > ed.numRows() {
+		to = ed.numRows()
+	}
+	for i := from; i < to; i++ {
+		row := ed.row(i)
+		if row.marked {
+			continue
+		}
+		ed.markComments(row)
+		ed.markTodos(row)
+		if spell {
+			ed.spellCheckRow(row)
+		}
+		if ed.cfg.indentLint {
+			ed.markBadIndent(row)
+		}
+		if matches {
+			ed.markMatches(row)
+		}
+		row.marked = true
+	}
+}
+
+// Re-highlight row at, and the rows after it for as long as the state they
+// start in keeps changing, e.g. when a conflict marker or the start of a
+// block comment appears or goes away.
+func (ed *Editor) updateSyntax(at int) {
+	for i := at; i < len(ed.rows); i++ {
+		state := ST_NONE
+		if i > 0 {
+			state = ed.rows[i-1].hlState
+		}
+		old := ed.rows[i].hlState
+		if ed.rows[i].highlight(state, ed.syntax) == old && i > at {
+			return
+		}
+	}
+}
+
+// Git conflict markers are 7 marker characters, optionally followed by a
+// space and a label, e.g. "<<<<<<< HEAD".
+func isConflictMarker(line string, c byte) bool {
+	if len(line) < 7 || (len(line) > 7 && line[7] != ' ') {
+		return false
+	}
+	return line[:7] == strings.Repeat(string(c), 7)
+}
+
+// Classify a line inside or around a merge conflict. Markers only count in
+// the right order, so e.g. a "=======" heading underline outside a conflict
+// is left alone.
+func conflictLine(line string, state uint8) (class, next uint8) {
+	switch {
+	case isConflictMarker(line, '<'):
+		return HL_CONFLICT_MARKER, ST_OURS
+	case isConflictMarker(line, '|') && state == ST_OURS:
+		return HL_CONFLICT_MARKER, ST_BASE
+	case isConflictMarker(line, '=') && (state == ST_OURS || state == ST_BASE):
+		return HL_CONFLICT_MARKER, ST_THEIRS
+	case isConflictMarker(line, '>') && state == ST_THEIRS:
+		return HL_CONFLICT_MARKER, ST_NONE
+	}
+	switch state {
+	case ST_OURS:
+		return HL_OURS, state
+	case ST_BASE:
+		return HL_BASE, state
+	case ST_THEIRS:
+		return HL_THEIRS, state
+	}
+	return HL_NORMAL, state
+}
+
+// SGR parameters a highlight class is drawn with, "" for the default. With
+// colors off only attributes such as bold and underline are used.
+func (ed *Editor) hlColor(hl uint8) string {
+	if !ed.cfg.color {
+		return hlAttr(hl)
+	}
+	switch hl {
+	case HL_CONFLICT_MARKER:
+		// Bold and inverted.
+		return "1;7"
+	case HL_OURS:
+		// Dark green background.
+		return "48;5;22"
+	case HL_BASE:
+		// Grey background.
+		return "48;5;238"
+	case HL_THEIRS:
+		// Dark blue background.
+		return "48;5;17"
+	case HL_SPELL:
+		// Red and underlined.
+		return "4;31"
+	case HL_CONTROL:
+		// Inverted, like less.
+		return "7"
+	case HL_FOLD:
+		// Cyan.
+		return "36"
+	case HL_EXTENDS:
+		// Magenta.
+		return "35"
+	case HL_COLORCOLUMN:
+		// Dark grey background.
+		return "48;5;236"
+	case HL_LONG:
+		// Red background.
+		return "41"
+	case HL_TRAILING:
+		// Dark red background.
+		return "48;5;52"
+	case HL_TODO:
+		// Black on yellow.
+		return "30;43"
+	case HL_BAD_INDENT:
+		// Orange background.
+		return "48;5;130"
+	case HL_CROSSHAIR:
+		// Near black background, darker than color columns.
+		return "48;5;235"
+	case HL_MATCH:
+		// Black on cyan.
+		return "30;46"
+	case HL_DIAG_SIGN:
+		// Bold red.
+		return "1;31"
+	case HL_DIAG_LINE:
+		// Dark purple background.
+		return "48;5;53"
+	case HL_PAIR:
+		// Bold and underlined.
+		return "1;4"
+	case HL_CURSOR:
+		// Inverted, like the terminal's cursor.
+		return "7"
+	case HL_NUMBER, HL_MINIMAP:
+		// Grey.
+		return "38;5;244"
+	case HL_MINIMAP_VIEW:
+		// White on grey, like the grey of a base section.
+		return "97;48;5;238"
+	case HL_DIFF_ADD:
+		// Green.
+		return "32"
+	case HL_DIFF_DEL:
+		// Red.
+		return "31"
+	case HL_DIFF_HUNK:
+		// Cyan, like fold markers.
+		return "36"
+	case HL_COMMENT:
+		// Grey, a shade lighter than line numbers.
+		return "38;5;246"
+	}
+	return ""
+}
+
+// Attributes for a highlight class on a terminal without colors. Conflict
+// sections, color columns, rows with diagnostics, comments and diff lines
+// other than hunk headers go unmarked.
+func hlAttr(hl uint8) string {
+	switch hl {
+	case HL_CONFLICT_MARKER:
+		// Bold and inverted, as with colors.
+		return "1;7"
+	case HL_CONTROL, HL_TRAILING, HL_BAD_INDENT, HL_MATCH, HL_MINIMAP_VIEW, HL_CURSOR:
+		// Inverted.
+		return "7"
+	case HL_SPELL, HL_LONG, HL_PAIR:
+		// Underlined.
+		return "4"
+	case HL_FOLD, HL_EXTENDS, HL_TODO, HL_DIAG_SIGN, HL_DIFF_HUNK:
+		// Bold.
+		return "1"
+	}
+	return ""
+}
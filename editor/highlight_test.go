@@ -0,0 +1,48 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBlockCommentAcrossRows(t *testing.T) {
+	path := writeTemp(t, "a.go", "a := 1\nb := 2\nc := 3\n// d\n")
+	// Which of the four rows are all comment, and the cursor row is
+	// typed on.
+	comments := func(ed *Editor) string {
+		ed.refresh()
+		var b strings.Builder
+		for i := 0; i < 4; i++ {
+			all := len(ed.rows[i].hl) > 0
+			for _, hl := range ed.rows[i].hl {
+				all = all && hl == HL_COMMENT
+			}
+			if all {
+				b.WriteByte('c')
+			} else {
+				b.WriteByte('-')
+			}
+		}
+		return b.String()
+	}
+	tests := []struct {
+		keys, want string
+	}{
+		{"", "---c"},
+		// The rows below go into the comment, up to where it's closed.
+		{insertKey + "/*", "cccc"},
+		{insertKey + "/*\x1b[B\x1b[F*/", "cc-c"},
+		{insertKey + "/*\x1b[B\x1b[F*/\x1b[D\x7f", "cccc"},
+		// Taking the opener out again leaves them as they were.
+		{insertKey + "/*\x7f\x7f", "---c"},
+		{insertKey + "/*\x1b[B\x1b[F */\x1b[A\x1b[H\x1b[3~\x1b[3~", "---c"},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.autoClose = false
+		ed, _ := runKeys(t, cfg, path, tt.keys)
+		if got := comments(ed); got != tt.want {
+			t.Errorf("%q: comment rows %s, want %s", tt.keys, got, tt.want)
+		}
+	}
+}
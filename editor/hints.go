@@ -0,0 +1,104 @@
+package editor
+
+import "strings"
+
+// A key hint, the action of the key shown and what to call it, or when the
+// key isn't in the keymap, key set to the key itself.
+type keyHint struct {
+	action, key, label string
+}
+
+// Name of a key as hints show it, e.g. "Ctrl-Q", "pageup" or "/".
+func keyName(k EdKey) string {
+	for name, key := range keyNames {
+		if key == k {
+			return name
+		}
+	}
+	switch {
+	case k < ' ':
+		return "Ctrl-" + string(rune(k+'@'))
+	case k < 127:
+		return string(rune(k))
+	}
+	return "?"
+}
+
+// The key bound to action, or the command running it when none is. Of
+// several keys a named one such as backspace goes before the others, then
+// the lowest, so the choice stays the same.
+func (ed *Editor) keyFor(action string) string {
+	rank := func(k EdKey) int {
+		if keyName(k) != string(rune(k)) && k >= ' ' {
+			return 0
+		}
+		if k >= ' ' {
+			return 1
+		}
+		return 2
+	}
+	best, found := EdKey(0), false
+	for k, a := range ed.cfg.keymap {
+		if a == action && (!found || rank(k) < rank(best) || (rank(k) == rank(best) && k < best)) {
+			best, found = k, true
+		}
+	}
+	if !found {
+		return ":" + action
+	}
+	return keyName(best)
+}
+
+// The hints for what the editor is doing: with more than one cursor, while
+// typing, with search matches marked, or else the keys to start with. Keys
+// the editor handles itself in a state, such as Escape, have key set.
+func (ed *Editor) keyHints() []keyHint {
+	switch {
+	case len(ed.cursors) > 0:
+		return []keyHint{{key: "esc", label: "one cursor"}, {action: "addcursor", label: "add cursor"}}
+	case ed.typing:
+		other := "overwrite"
+		if ed.overwrite {
+			other = "insert"
+		}
+		return []keyHint{
+			{key: "esc", label: "stop typing"},
+			{action: "insert", label: other},
+			{action: "addcursor", label: "add cursor"},
+		}
+	case ed.showMatches():
+		return []keyHint{
+			{action: "findnext", label: "next"},
+			{action: "findprev", label: "previous"},
+			{action: "nohl", label: "unmark"},
+			{action: "find", label: "find"},
+		}
+	}
+	return []keyHint{
+		{action: "quit", label: "quit"},
+		{action: "command", label: "command"},
+		{action: "find", label: "find"},
+		{action: "insert", label: "type"},
+	}
+}
+
+// hints as "key = label" joined by " | ", as many of them as fit in width,
+// all of them for a width below 0.
+func (ed *Editor) hintText(hints []keyHint, width int) string {
+	var b strings.Builder
+	for _, h := range hints {
+		key := h.key
+		if key == "" {
+			key = ed.keyFor(h.action)
+		}
+		s := key + " = " + h.label
+		if b.Len() > 0 {
+			s = " | " + s
+		}
+		if width >= 0 && b.Len()+len(s) > width {
+			break
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyName(t *testing.T) {
+	for k, want := range map[EdKey]string{
+		0x1f & 'q': "Ctrl-Q",
+		0x1e:       "Ctrl-^",
+		':':        ":",
+		PG_UP:      "pageup",
+		127:        "backspace",
+		'\r':       "enter",
+	} {
+		if got := keyName(k); got != want {
+			t.Errorf("keyName(%d) = %q, want %q", k, got, want)
+		}
+	}
+}
+
+func TestKeyHints(t *testing.T) {
+	tests := []struct {
+		name, keys string
+		width      int
+		want       string
+	}{
+		{"normal", "", 80, "Ctrl-Q = quit | : = command | / = find | insert = type"},
+		{"cut to fit", "", 30, "Ctrl-Q = quit | : = command"},
+		{"typing", insertKey, 80,
+			"esc = stop typing | insert = overwrite | Ctrl-D = add cursor"},
+		{"overwriting", insertKey + insertKey, 80, "esc = stop typing | insert = insert | Ctrl-D = add cursor"},
+		{"cursors", "\x04", 80, "esc = one cursor | Ctrl-D = add cursor"},
+		{"matches", "/b\r", 80, "n = next | N = previous | :nohl = unmark | / = find"},
+		{"matches unmarked", "/b\r:nohl\r", 80, "Ctrl-Q = quit | : = command | / = find | insert = type"},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.hlSearch = true
+		ed, _ := runKeys(t, cfg, writeTemp(t, "a.txt", "a\nb\na\n"), tt.keys)
+		if got := ed.hintText(ed.keyHints(), tt.width); got != tt.want {
+			t.Errorf("%s: hints %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Keys come from the keymap, so hints follow bindings.
+	cfg := DefaultConfig()
+	delete(cfg.keymap, 0x1f&'q')
+	if err := cfg.set("bind=ctrl-x:quit"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.set("bind=ctrl-s:find"); err != nil {
+		t.Fatal(err)
+	}
+	ed, _ := runKeys(t, cfg, "", "")
+	got := ed.hintText(ed.keyHints(), 80)
+	if !strings.HasPrefix(got, "Ctrl-X = quit | : = command | / = find") {
+		t.Errorf("hints %q after binding", got)
+	}
+	if !strings.HasPrefix(ed.statusmsg, "HELP: Ctrl-X = quit | : = command") {
+		t.Errorf("status %q", ed.statusmsg)
+	}
+
+	// Drawn in the message bar when there is no message, unless turned
+	// off.
+	for _, on := range []bool{true, false} {
+		ed, out := frameEditor(t, 40, 5, "a.txt", "a\n")
+		ed.cfg.keyHints = on
+		ed.refresh()
+		bar := screenLines(out.String())[5]
+		if want := "Ctrl-Q = quit | : = command | / = find"; (bar == want) != on {
+			t.Errorf("keyhints %v: message bar %q", on, bar)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package editor
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// One line of a .gitignore file.
+type ignoreRule struct {
+	// Directory of the .gitignore, relative to where the walk started and
+	// with forward slashes. The rule only applies below it.
+	dir string
+	// Pattern split at slashes, "**" standing for any number of directories.
+	segments []string
+	// "!pattern" brings back what an earlier rule ignored.
+	negate bool
+	// "pattern/" only matches directories.
+	dirOnly bool
+	// A pattern with a slash other than a trailing one is matched against
+	// the path from dir, one without against the name alone.
+	anchored bool
+}
+
+// The rules in effect for a directory walk, in the order git applies them:
+// the last rule that matches a path decides.
+type ignorer struct {
+	rules []ignoreRule
+}
+
+// Parse a gitignore line found in dir. ok is false for blank lines and
+// comments. Escaped trailing spaces and character classes spanning a slash
+// aren't supported.
+func parseIgnoreRule(dir, line string) (rule ignoreRule, ok bool) {
+	line = strings.TrimRight(line, " \r")
+	if line == "" || line[0] == '#' {
+		return rule, false
+	}
+	rule.dir = dir
+	if line[0] == '!' {
+		rule.negate = true
+		line = line[1:]
+	} else if line[0] == '\\' {
+		// "\#" and "\!" for names starting with those.
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.HasPrefix(line, "/") {
+		rule.anchored = true
+		line = line[1:]
+	}
+	if line == "" {
+		return rule, false
+	}
+	rule.anchored = rule.anchored || strings.Contains(line, "/")
+	rule.segments = strings.Split(line, "/")
+	return rule, true
+}
+
+// Add patterns given as gitignore lines, for the directory the walk starts
+// in.
+func (ig *ignorer) add(patterns []string) {
+	for _, p := range patterns {
+		if rule, ok := parseIgnoreRule(".", p); ok {
+			ig.rules = append(ig.rules, rule)
+		}
+	}
+}
+
+// Add the rules of dir's .gitignore, if it has one.
+func (ig *ignorer) load(dir string) {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	dir = filepath.ToSlash(dir)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if rule, ok := parseIgnoreRule(dir, s.Text()); ok {
+			ig.rules = append(ig.rules, rule)
+		}
+	}
+}
+
+// Whether the file or directory at p, relative to where the walk started,
+// is ignored.
+func (ig *ignorer) ignored(p string, isDir bool) bool {
+	p = filepath.ToSlash(p)
+	ignored := false
+	for _, rule := range ig.rules {
+		if rule.negate != ignored || (rule.dirOnly && !isDir) {
+			// Can't change the outcome.
+			continue
+		}
+		rel := p
+		if rule.dir != "." {
+			if !strings.HasPrefix(p, rule.dir+"/") {
+				continue
+			}
+			rel = p[len(rule.dir)+1:]
+		}
+		var match bool
+		if rule.anchored {
+			match = matchSegments(rule.segments, strings.Split(rel, "/"))
+		} else {
+			match, _ = path.Match(rule.segments[0], path.Base(rel))
+		}
+		if match {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// Match path segments against pattern segments, where "**" matches any
+// number of segments and the others are globs.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], segments[0])
+	return ok && matchSegments(pattern[1:], segments[1:])
+}
@@ -0,0 +1,97 @@
+package editor
+
+import "strconv"
+
+// Rows looked at when guessing the indentation of a file.
+const INDENT_SAMPLE_ROWS = 1000
+
+// Guess from leading whitespace whether the file is indented with tabs or
+// with spaces, and how many, then set expandTabs and shiftWidth to match so
+// the buffer follows the file's own style. Leave the settings alone if there
+// is no indentation to go by.
+func (ed *Editor) detectIndent() {
+	tabs, spaces := 0, 0
+	// How often each step in space indentation between consecutive
+	// indented lines occurs, e.g. 4 for a file indented by 4 spaces.
+	steps := make(map[int]int)
+	prev := 0
+	for i := 0; i < ed.numRows() && i < INDENT_SAMPLE_ROWS; i++ {
+		chars := ed.row(i).chars
+		if len(chars) == 0 {
+			continue
+		}
+		if chars[0] == '\t' {
+			tabs++
+			continue
+		}
+		n := 0
+		for n < len(chars) && chars[n] == ' ' {
+			n++
+		}
+		if n == len(chars) {
+			// Whitespace only, says nothing about the style.
+			continue
+		}
+		if n > 0 {
+			spaces++
+		}
+		if n > prev {
+			steps[n-prev]++
+		}
+		prev = n
+	}
+	if tabs == 0 && spaces == 0 {
+		return
+	}
+	if tabs >= spaces {
+		ed.cfg.expandTabs = false
+		return
+	}
+	best, count := 0, 0
+	for step, c := range steps {
+		if c > count || (c == count && step < best) {
+			best, count = step, c
+		}
+	}
+	// Ignore odd outliers such as a single space of alignment.
+	if best < 2 || best > 8 {
+		return
+	}
+	ed.cfg.expandTabs = true
+	ed.cfg.shiftWidth = best
+}
+
+// Columns per indent level. Tab indentation goes a tab per level, so it is
+// always tabStop.
+func (ed *Editor) indentWidth() int {
+	if ed.cfg.expandTabs && ed.cfg.shiftWidth > 0 {
+		return ed.cfg.shiftWidth
+	}
+	return ed.cfg.tabStop
+}
+
+// Short description of the indentation style for the status bar.
+func (ed *Editor) indentInfo() string {
+	if ed.cfg.expandTabs {
+		return "spaces:" + strconv.Itoa(ed.indentWidth())
+	}
+	return "tabs"
+}
+
+// Show the indentation settings in effect for the buffer in full.
+func (ed *Editor) showIndent() {
+	expand := "noexpandtab"
+	if ed.cfg.expandTabs {
+		expand = "expandtab"
+	}
+	ed.setStatus("tabstop=%d shiftwidth=%d %s (%s)", ed.cfg.tabStop, ed.cfg.shiftWidth, expand, ed.indentInfo())
+}
+
+// Index of the first character that is not a space or tab, len(s) if none.
+func firstNonBlank(s string) int {
+	i := 0
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,311 @@
+package editor
+
+import (
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// How long to wait for the rest of an escape sequence before taking what
+// came so far as separate keys, e.g. a lone Escape. Sequences arrive split
+// over slow links, but a person can't follow Escape with "[A" this fast.
+const ESC_TIMEOUT = 50 * time.Millisecond
+
+// Reads the terminal in the background so keys can be waited for with a
+// timeout. Bytes that turn out not to belong to a key are kept for the next.
+// Waiting for a key is cut short by a resize so the screen is redrawn.
+type keyReader struct {
+	bytes   chan byte
+	pending []byte
+	resize  <-chan os.Signal
+	// Receives a signal to stop, after which only STOP keys are read. The
+	// input ending stops the reader too.
+	stop    <-chan os.Signal
+	stopped bool
+	// Cuts waiting short like a resize, while a file is followed.
+	tick <-chan time.Time
+	// Cuts waiting short when background work is done, see wakeUp.
+	wake chan struct{}
+	// Text of the last PASTE read.
+	pasted string
+	// When the terminal was last asked for its clipboard and hasn't
+	// answered, see readClipboard.
+	queried time.Time
+}
+
+func newKeyReader(r io.Reader, resize <-chan os.Signal) *keyReader {
+	kr := &keyReader{bytes: make(chan byte, 1024), resize: resize, wake: make(chan struct{}, 1)}
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			n, err := r.Read(buf)
+			for _, b := range buf[:n] {
+				kr.bytes <- b
+			}
+			if err != nil {
+				close(kr.bytes)
+				return
+			}
+		}
+	}()
+	return kr
+}
+
+// Next byte, waiting at most timeout when it is not 0. ok is false when
+// none came in time, or with no timeout when the terminal was resized, tick
+// fired or wakeUp was called.
+func (kr *keyReader) next(timeout time.Duration) (b byte, ok bool) {
+	if len(kr.pending) > 0 {
+		b, kr.pending = kr.pending[0], kr.pending[1:]
+		return b, true
+	}
+	if timeout == 0 {
+		select {
+		case b, ok = <-kr.bytes:
+			if !ok {
+				// The terminal went away, e.g. the ssh connection
+				// dropped, or a key script ran out. Stopping is all
+				// that's left, keys asking to quit could be refused.
+				kr.stopped = true
+				return 0, false
+			}
+			return b, true
+		case <-kr.resize:
+			return 0, false
+		case <-kr.stop:
+			kr.stopped = true
+			return 0, false
+		case <-kr.tick:
+			return 0, false
+		case <-kr.wake:
+			return 0, false
+		}
+	}
+	select {
+	case b, ok = <-kr.bytes:
+		return b, ok
+	case <-time.After(timeout):
+		return 0, false
+	}
+}
+
+// Have the key being waited for, if any, cut short so the screen is redrawn.
+// Safe to call from any goroutine, wakes coming in together count once.
+func (kr *keyReader) wakeUp() {
+	select {
+	case kr.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Rows or lines a long scan goes through between checks for Escape.
+const CANCEL_CHECK_ROWS = 4096
+
+// Whether Escape was typed while a long operation ran, e.g. a search through
+// a huge file, to give up on it. Other keys typed meanwhile are kept, those
+// after the Escape too.
+func (kr *keyReader) cancelled() bool {
+	select {
+	case <-kr.stop:
+		kr.stopped = true
+	default:
+	}
+	if kr.stopped {
+		// Whatever it is can't be waited for any more.
+		return true
+	}
+	for more := true; more; {
+		select {
+		case b, ok := <-kr.bytes:
+			if !ok {
+				// Closed, next will turn that into a STOP.
+				return false
+			}
+			kr.pending = append(kr.pending, b)
+		default:
+			more = false
+		}
+	}
+	for i, b := range kr.pending {
+		// An Escape starting a sequence is some other key, e.g. an arrow.
+		if b == 0x1b && (i+1 == len(kr.pending) || (kr.pending[i+1] != '[' && kr.pending[i+1] != 'O')) {
+			kr.pending = append(kr.pending[:i], kr.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Whether a scan at step i should stop because Escape was typed. Reports
+// the cancel in the status bar. Only every CANCEL_CHECK_ROWS steps look.
+func (ed *Editor) scanCancelled(i int) bool {
+	if i%CANCEL_CHECK_ROWS != CANCEL_CHECK_ROWS-1 || !ed.keys.cancelled() {
+		return false
+	}
+	ed.setStatus("Cancelled")
+	return true
+}
+
+// Whether more typed input is already waiting to be read.
+func (kr *keyReader) waiting() bool {
+	return len(kr.pending) > 0 || len(kr.bytes) > 0
+}
+
+// Wait for a keypress and return its value. Escape sequences are read up to
+// their final byte even when they arrive in pieces, e.g. \x1b[A for arrow up.
+func (kr *keyReader) readKey() EdKey {
+	if kr.stopped {
+		return STOP
+	}
+	b, ok := kr.next(0)
+	if kr.stopped {
+		return STOP
+	}
+	if !ok {
+		return RESIZE
+	}
+	if b != 0x1b {
+		return EdKey(b)
+	}
+	b, ok = kr.next(ESC_TIMEOUT)
+	if !ok {
+		return EdKey(0x1b)
+	}
+	if b == 'O' {
+		// An SS3 sequence, sent for arrows, Home and End in application
+		// cursor mode, e.g. \x1bOA for arrow up, and for F1 to F4.
+		return kr.readSS3()
+	}
+	if b == ']' && !kr.queried.IsZero() && time.Since(kr.queried) < OSC52_LATE {
+		// The terminal answering a clipboard query too late, not keys.
+		kr.queried = time.Time{}
+		kr.readOSC()
+		return 0
+	}
+	if b != '[' {
+		// Escape followed by another key, which goes back in front of
+		// the bytes still pending.
+		kr.pending = append([]byte{b}, kr.pending...)
+		return EdKey(0x1b)
+	}
+	// A CSI sequence: parameter bytes, then a final byte in @ to ~.
+	var params []byte
+	for {
+		b, ok = kr.next(ESC_TIMEOUT)
+		if !ok {
+			// Cut short, hand the bytes on rather than guess.
+			kr.pending = append(append(kr.pending, '['), params...)
+			return EdKey(0x1b)
+		}
+		if b >= 0x40 && b <= 0x7e {
+			break
+		}
+		params = append(params, b)
+	}
+	switch b {
+	// Arrow keys set, also with modifiers as in \x1b[1;5A. Ctrl-Left and
+	// Ctrl-Right move by words.
+	case 'A':
+		return ARW_UP
+	case 'B':
+		return ARW_DOWN
+	case 'C':
+		if strings.HasSuffix(string(params), ";5") {
+			return CTRL_RIGHT
+		}
+		return ARW_RIGHT
+	case 'D':
+		if strings.HasSuffix(string(params), ";5") {
+			return CTRL_LEFT
+		}
+		return ARW_LEFT
+	// Focus in and out as <esc>[I and <esc>[O .
+	case 'I':
+		if len(params) == 0 {
+			return FOCUS_IN
+		}
+	case 'O':
+		if len(params) == 0 {
+			return FOCUS_OUT
+		}
+	// Home and End as <esc>[H and <esc>[F .
+	case 'H':
+		return HOME_KEY
+	case 'F':
+		return END_KEY
+	case '~':
+		// Page Up <esc>[5~ and Page Down <esc>[6~ .
+		// Home is sent as <esc>[1~ or <esc>[7~, End as <esc>[4~ or
+		// <esc>[8~ depending on the terminal.
+		switch string(params) {
+		case "5":
+			return PG_UP
+		case "6":
+			return PG_DOWN
+		case "1", "7":
+			return HOME_KEY
+		case "4", "8":
+			return END_KEY
+		// Pasted text follows <esc>[200~ .
+		case "200":
+			return kr.readPaste()
+		// Insert as <esc>[2~, Delete as <esc>[3~ .
+		case "2":
+			return INSERT_KEY
+		case "3":
+			return DEL_KEY
+		}
+	}
+	// A sequence for a key exa doesn't know, ignore it whole.
+	return 0
+}
+
+// The character starting with byte b as typed, reading the rest of it when
+// it takes more than one byte in UTF-8. Bytes that don't belong to it are
+// kept for the next key, a broken one gives utf8.RuneError.
+func (kr *keyReader) readRune(b byte) rune {
+	buf := []byte{b}
+	for !utf8.FullRune(buf) {
+		c, ok := kr.next(ESC_TIMEOUT)
+		if !ok {
+			break
+		}
+		if !utf8.RuneStart(c) {
+			buf = append(buf, c)
+			continue
+		}
+		kr.pending = append([]byte{c}, kr.pending...)
+		break
+	}
+	r, _ := utf8.DecodeRune(buf)
+	return r
+}
+
+// The key of an <esc>O sequence, the <esc>O read already.
+func (kr *keyReader) readSS3() EdKey {
+	b, ok := kr.next(ESC_TIMEOUT)
+	if !ok {
+		// Escape and O typed, not a sequence.
+		kr.pending = append([]byte{'O'}, kr.pending...)
+		return EdKey(0x1b)
+	}
+	switch b {
+	case 'A':
+		return ARW_UP
+	case 'B':
+		return ARW_DOWN
+	case 'C':
+		return ARW_RIGHT
+	case 'D':
+		return ARW_LEFT
+	case 'H':
+		return HOME_KEY
+	case 'F':
+		return END_KEY
+	}
+	// F1 to F4 as <esc>OP to <esc>OS, and keypad keys, aren't bound to
+	// anything.
+	return 0
+}
@@ -0,0 +1,110 @@
+package editor
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+// Keys read from text written a byte at a time, pause apart.
+func readKeysSlowly(t *testing.T, text string, pause time.Duration) []EdKey {
+	t.Helper()
+	r, w := io.Pipe()
+	go func() {
+		for i := 0; i < len(text); i++ {
+			time.Sleep(pause)
+			w.Write([]byte{text[i]})
+		}
+		w.Close()
+	}()
+	kr := newKeyReader(r, nil)
+	var keys []EdKey
+	for {
+		k := kr.readKey()
+		if k == STOP {
+			return keys
+		}
+		keys = append(keys, k)
+	}
+}
+
+func TestReadKeySplitSequences(t *testing.T) {
+	tests := []struct {
+		name, text string
+		want       []EdKey
+	}{
+		{"arrows", "\x1b[A\x1b[B\x1b[C\x1b[D", []EdKey{ARW_UP, ARW_DOWN, ARW_RIGHT, ARW_LEFT}},
+		{"with parameters", "\x1b[1;5C\x1b[5~\x1b[6~", []EdKey{CTRL_RIGHT, PG_UP, PG_DOWN}},
+		{"between letters", "a\x1b[Hb", []EdKey{'a', HOME_KEY, 'b'}},
+		{"unknown sequence dropped whole", "\x1b[15~x", []EdKey{0, 'x'}},
+	}
+	for _, tt := range tests {
+		// Well within ESC_TIMEOUT between bytes, as over a slow link.
+		got := readKeysSlowly(t, tt.text, ESC_TIMEOUT/5)
+		if !equalKeys(got, tt.want) {
+			t.Errorf("%s: keys %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadKeyLoneEscape(t *testing.T) {
+	// Escape and then keys typed after it, too slow to be a sequence.
+	got := readKeysSlowly(t, "\x1b[A", 2*ESC_TIMEOUT)
+	if want := []EdKey{0x1b, '[', 'A'}; !equalKeys(got, want) {
+		t.Errorf("keys %v, want %v", got, want)
+	}
+}
+
+func TestReadKeyPendingOrder(t *testing.T) {
+	// Escape and a key typed together with more keys, as cancelled leaves
+	// them pending during a long scan.
+	bytes := make(chan byte)
+	close(bytes)
+	kr := &keyReader{bytes: bytes, pending: []byte("\x1bxab\x1bOcd")}
+	var got []EdKey
+	for k := kr.readKey(); k != STOP; k = kr.readKey() {
+		got = append(got, k)
+	}
+	want := []EdKey{0x1b, 'x', 'a', 'b', 0, 'd'}
+	if !equalKeys(got, want) {
+		t.Errorf("keys %v, want %v", got, want)
+	}
+}
+
+func equalKeys(a, b []EdKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReadKeyBothEncodings(t *testing.T) {
+	// CSI as in normal mode, SS3 as in application cursor mode.
+	csi := "\x1b[A\x1b[B\x1b[C\x1b[D\x1b[H\x1b[F"
+	ss3 := "\x1bOA\x1bOB\x1bOC\x1bOD\x1bOH\x1bOF"
+	want := []EdKey{ARW_UP, ARW_DOWN, ARW_RIGHT, ARW_LEFT, HOME_KEY, END_KEY}
+	for _, text := range []string{csi, ss3} {
+		if got := readKeysSlowly(t, text, 0); !equalKeys(got, want) {
+			t.Errorf("keys %v from %q, want %v", got, text, want)
+		}
+	}
+	// Function keys are read whole and ignored, Escape O alone is two keys.
+	if got, want := readKeysSlowly(t, "\x1bOPx", 0), []EdKey{0, 'x'}; !equalKeys(got, want) {
+		t.Errorf("keys %v after F1, want %v", got, want)
+	}
+	if got, want := readKeysSlowly(t, "\x1bO", 0), []EdKey{0x1b, 'O'}; !equalKeys(got, want) {
+		t.Errorf("keys %v for Escape O, want %v", got, want)
+	}
+}
+
+func TestSS3KeysMoveTheCursor(t *testing.T) {
+	ed, _ := runKeys(t, DefaultConfig(), writeTemp(t, "a.txt", "ab\ncd\n"), "\x1bOB\x1bOC\x1bOF\x1bOD")
+	if ed.cy != 1 || ed.cx != 1 {
+		t.Errorf("cursor at %d:%d, want 1:1", ed.cy, ed.cx)
+	}
+}
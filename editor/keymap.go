@@ -0,0 +1,221 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Maps keys to the name of the action they trigger.
+type Keymap map[EdKey]string
+
+// Editor actions that can be bound to a key. Return false to quit.
+var actions map[string]func(ed *Editor) bool
+
+// Filled in init since some actions lead back to the keymap, e.g. ":set bind".
+func init() {
+	actions = map[string]func(ed *Editor) bool{
+		"quit":        func(ed *Editor) bool { return ed.quit() },
+		"command":     func(ed *Editor) bool { return ed.commandMode() },
+		"left":        func(ed *Editor) bool { ed.moveCursor(ARW_LEFT); return true },
+		"right":       func(ed *Editor) bool { ed.moveCursor(ARW_RIGHT); return true },
+		"up":          func(ed *Editor) bool { ed.moveCursor(ARW_UP); return true },
+		"down":        func(ed *Editor) bool { ed.moveCursor(ARW_DOWN); return true },
+		"home":        func(ed *Editor) bool { ed.moveCursor(HOME_KEY); return true },
+		"end":         func(ed *Editor) bool { ed.moveCursor(END_KEY); return true },
+		"pageup":      func(ed *Editor) bool { ed.page(true); return true },
+		"pagedown":    func(ed *Editor) bool { ed.page(false); return true },
+		"delwordback": func(ed *Editor) bool { ed.delWordBack(); return true },
+		"wordleft":    func(ed *Editor) bool { ed.wordLeft(); return true },
+		"wordright":   func(ed *Editor) bool { ed.wordRight(); return true },
+		"paranext":    func(ed *Editor) bool { ed.jumpParagraph(1); return true },
+		"paraprev":    func(ed *Editor) bool { ed.jumpParagraph(-1); return true },
+		"backspace":   func(ed *Editor) bool { ed.backspace(); return true },
+		"delete":      func(ed *Editor) bool { ed.deleteChar(); return true },
+		"tab":         func(ed *Editor) bool { ed.tab(); return true },
+		"insert":      func(ed *Editor) bool { ed.toggleInsert(); return true },
+		"addcursor":   func(ed *Editor) bool { ed.addCursor(); return true },
+		"follow":      func(ed *Editor) bool { ed.toggleFollow(); return true },
+		"close":       func(ed *Editor) bool { ed.close(); return true },
+		"complete":    func(ed *Editor) bool { ed.complete(); return true },
+		"repeat":      func(ed *Editor) bool { ed.repeatChange(); return true },
+		"undo":        func(ed *Editor) bool { ed.undo(); return true },
+		"redo":        func(ed *Editor) bool { ed.redo(); return true },
+		"copyline":    func(ed *Editor) bool { ed.copyLine(); return true },
+		"cutline":     func(ed *Editor) bool { ed.cutLine(); return true },
+		"copyword":    func(ed *Editor) bool { ed.copyWord(); return true },
+		"paste":       func(ed *Editor) bool { ed.pasteClipboard(); return true },
+		"yankpop":     func(ed *Editor) bool { ed.yankPop(); return true },
+		"save":        func(ed *Editor) bool { return ed.execCommand("w") },
+		"savecopy":    func(ed *Editor) bool { ed.writeTo(""); return true },
+		"crosshair":   func(ed *Editor) bool { ed.cfg.crosshair = !ed.cfg.crosshair; return true },
+		"minimap":     func(ed *Editor) bool { ed.cfg.minimap = !ed.cfg.minimap; return true },
+		"alternate":   func(ed *Editor) bool { ed.alternate(); return true },
+		"newline":     func(ed *Editor) bool { ed.newline(); return true },
+		"gotofile":    func(ed *Editor) bool { ed.gotoFile(); return true },
+		"tag":         func(ed *Editor) bool { ed.jumpToTag(""); return true },
+		"tagpop":      func(ed *Editor) bool { ed.popTag(); return true },
+		"explore":     func(ed *Editor) bool { ed.explore(); return true },
+		"todonext":    func(ed *Editor) bool { ed.jumpTodo(1); return true },
+		"find":        func(ed *Editor) bool { ed.find(); return true },
+		"findnext":    func(ed *Editor) bool { ed.jumpMatch(1); return true },
+		"findprev":    func(ed *Editor) bool { ed.jumpMatch(-1); return true },
+		"nohl":        func(ed *Editor) bool { ed.noHighlight(); return true },
+		"ignorecase":  func(ed *Editor) bool { ed.toggleSearchOption("ignorecase", &ed.cfg.ignoreCase); return true },
+		"wholeword":   func(ed *Editor) bool { ed.toggleSearchOption("wholeword", &ed.cfg.wholeWord); return true },
+		"diagnext":    func(ed *Editor) bool { ed.jumpDiagnostic(1); return true },
+		"diagprev":    func(ed *Editor) bool { ed.jumpDiagnostic(-1); return true },
+		"diagclear":   func(ed *Editor) bool { ed.clearDiagnostics(); return true },
+		"todoprev":    func(ed *Editor) bool { ed.jumpTodo(-1); return true },
+		"indentnext":  func(ed *Editor) bool { ed.jumpBadIndent(); return true },
+		"indent":      func(ed *Editor) bool { ed.showIndent(); return true },
+		"spellnext":   func(ed *Editor) bool { ed.jumpMisspelled(1); return true },
+		"spellprev":   func(ed *Editor) bool { ed.jumpMisspelled(-1); return true },
+		"wordcount":   func(ed *Editor) bool { ed.wordCount(); return true },
+		"replaceword": func(ed *Editor) bool { ed.replaceWord(); return true },
+		"filter":      func(ed *Editor) bool { ed.filterPrompt(); return true },
+		"grep":        func(ed *Editor) bool { ed.grepPrompt(); return true },
+		"diff":        func(ed *Editor) bool { ed.diffWithDisk(); return true },
+		"pathdisplay": func(ed *Editor) bool { ed.cyclePathDisplay(); return true },
+		"pane":        func(ed *Editor) bool { ed.togglePaneFocus(); return true },
+		"fold":        func(ed *Editor) bool { ed.fold(); return true },
+		"unfold":      func(ed *Editor) bool { ed.unfold(); return true },
+		// Scroll the cursor row to the middle, top or bottom of the screen.
+		"center": func(ed *Editor) bool { ed.scrollCursorTo(ed.screenrows / 2); return true },
+		"top":    func(ed *Editor) bool { ed.scrollCursorTo(0); return true },
+		"bottom": func(ed *Editor) bool { ed.scrollCursorTo(ed.screenrows - 1); return true },
+		// Run statuscmd now rather than when it's due.
+		"statusrefresh": func(ed *Editor) bool { ed.updateStatusHook(true); return true },
+	}
+}
+
+// Actions repeated by a count prefix.
+var movements = map[string]bool{
+	"left":      true,
+	"right":     true,
+	"up":        true,
+	"down":      true,
+	"pageup":    true,
+	"pagedown":  true,
+	"wordleft":  true,
+	"wordright": true,
+	"findnext":  true,
+	"findprev":  true,
+	"paranext":  true,
+	"paraprev":  true,
+}
+
+// Actions that change the buffer in the same way wherever the cursor is,
+// so "repeat" can do them again.
+var changes = map[string]bool{
+	"backspace":   true,
+	"delete":      true,
+	"delwordback": true,
+	"tab":         true,
+	"newline":     true,
+	"paste":       true,
+}
+
+// Names of the non-ASCII and special keys accepted in bindings.
+var keyNames = map[string]EdKey{
+	"left":       ARW_LEFT,
+	"right":      ARW_RIGHT,
+	"up":         ARW_UP,
+	"down":       ARW_DOWN,
+	"pageup":     PG_UP,
+	"pagedown":   PG_DOWN,
+	"home":       HOME_KEY,
+	"end":        END_KEY,
+	"ctrl-left":  CTRL_LEFT,
+	"ctrl-right": CTRL_RIGHT,
+	"insert":     INSERT_KEY,
+	"delete":     DEL_KEY,
+	"esc":        0x1b,
+	"tab":        '\t',
+	"enter":      '\r',
+	"space":      ' ',
+	// Backspace is sent as DEL (127) or CTRL+h.
+	"backspace": 127,
+}
+
+func defaultKeymap() Keymap {
+	return Keymap{
+		0x1f & 'q': "quit",
+		ARW_LEFT:   "left",
+		ARW_RIGHT:  "right",
+		ARW_UP:     "up",
+		ARW_DOWN:   "down",
+		PG_UP:      "pageup",
+		PG_DOWN:    "pagedown",
+		HOME_KEY:   "home",
+		END_KEY:    "end",
+		CTRL_LEFT:  "wordleft",
+		CTRL_RIGHT: "wordright",
+		INSERT_KEY: "insert",
+		DEL_KEY:    "delete",
+		':':        "command",
+		'.':        "repeat",
+		'u':        "undo",
+		0x1f & 'r': "redo",
+		'y':        "copyline",
+		'p':        "paste",
+		0x1f & 'k': "cutline",
+		0x1f & 'y': "yankpop",
+		'/':        "find",
+		'n':        "findnext",
+		'N':        "findprev",
+		'}':        "paranext",
+		'{':        "paraprev",
+		0x1f & 'w': "delwordback",
+		0x1f & 'l': "center",
+		0x1f & 'n': "complete",
+		127:        "backspace",
+		0x1f & 'h': "backspace",
+		// Ctrl-^ as in vi.
+		0x1e: "alternate",
+		'\r': "newline",
+		'\t': "tab",
+		// Ctrl-] and Ctrl-T as in vi.
+		0x1d:       "tag",
+		0x1f & 't': "tagpop",
+		0x1f & 'o': "pane",
+		0x1f & 'd': "addcursor",
+	}
+}
+
+// Parse a key name such as "ctrl-s", "pageup" or "x".
+func parseKey(name string) (EdKey, error) {
+	if k, ok := keyNames[strings.ToLower(name)]; ok {
+		return k, nil
+	}
+	if strings.HasPrefix(strings.ToLower(name), "ctrl-") && len(name) == 6 {
+		// By design, CTRL+char ASCII value can be calculated by bitwise-AND
+		// binary 00011111 (0x1f) with char.
+		c := strings.ToLower(name)[5]
+		if c >= 'a' && c <= 'z' {
+			return EdKey(0x1f & c), nil
+		}
+	}
+	if len(name) == 1 && name[0] > ' ' && name[0] < 127 {
+		return EdKey(name[0]), nil
+	}
+	return 0, fmt.Errorf("unknown key %q", name)
+}
+
+// Apply a "key:action" binding, e.g. "ctrl-s:save".
+func (km Keymap) bind(spec string) error {
+	// Split at the last colon so ":" itself can be bound.
+	idx := strings.LastIndexByte(spec, ':')
+	if idx <= 0 {
+		return fmt.Errorf("bad binding %q, want key:action", spec)
+	}
+	key, err := parseKey(strings.TrimSpace(spec[:idx]))
+	if err != nil {
+		return err
+	}
+	action := strings.TrimSpace(spec[idx+1:])
+	if _, ok := actions[action]; !ok {
+		return fmt.Errorf("unknown action %q", action)
+	}
+	km[key] = action
+	return nil
+}
@@ -0,0 +1,32 @@
+package editor
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestBindSave(t *testing.T) {
+	cfg, err := loadConfig(writeTemp(t, "config", "bind = ctrl-s:save\n"))
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if cfg.keymap[0x1f&'s'] != "save" {
+		t.Fatalf("ctrl-s bound to %q, want save", cfg.keymap[0x1f&'s'])
+	}
+	path := writeTemp(t, "a.txt", "a\n")
+	ed, code := runKeys(t, cfg, path, "\t\x13")
+	if ed.Dirty() || code != 0 {
+		t.Errorf("dirty %v, exit status %d after ctrl-s, want saved", ed.Dirty(), code)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "\ta\n" {
+		t.Errorf("saved %q, want %q", data, "\ta\n")
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	for _, spec := range []string{"ctrl-s:nosuchaction", "ctrl-?:save", "save", ":save"} {
+		if err := DefaultConfig().keymap.bind(spec); err == nil {
+			t.Errorf("bind %q: no error", spec)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package editor
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+)
+
+// A file too large to load into memory, viewed read-only. Only the offset of
+// each line start is kept and rows are read back from disk when needed.
+type lazyFile struct {
+	f       *os.File
+	size    int64
+	offsets []int64
+	// The last line indexed ended with a newline, the next byte starts a
+	// new one.
+	lineStart bool
+	// Rows read so far, dropped wholesale when it grows too big.
+	cache map[int]*Row
+}
+
+// Rows cached before the cache is emptied, a few screens worth.
+const LAZY_CACHE_ROWS = 1024
+
+// Open filename and index its lines. The file stays open until close.
+// progress is told how many bytes were indexed after each chunk. When it
+// returns false, indexing stops with errCancelled and the lines indexed so
+// far are all the file has.
+func openLazy(filename string, progress func(done int64) bool) (*lazyFile, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	lf := &lazyFile{f: f, cache: make(map[int]*Row), lineStart: true}
+	err = lf.index(progress)
+	if err != nil && err != errCancelled {
+		f.Close()
+		return nil, err
+	}
+	return lf, err
+}
+
+// Index the lines from where indexing last stopped to the end of the file,
+// e.g. what was appended since it was opened.
+func (lf *lazyFile) index(progress func(done int64) bool) error {
+	// A line that was cut short by the end of the file may go on now.
+	if !lf.lineStart && len(lf.offsets) > 0 {
+		delete(lf.cache, len(lf.offsets)-1)
+	}
+	buf := make([]byte, 1<<20)
+	for {
+		n, err := lf.f.Read(buf)
+		chunk := buf[:n]
+		for len(chunk) > 0 {
+			// A line is only counted once it has content, so a final
+			// newline does not add an empty row.
+			if lf.lineStart {
+				lf.offsets = append(lf.offsets, lf.size)
+				lf.lineStart = false
+			}
+			i := bytes.IndexByte(chunk, '\n')
+			if i < 0 {
+				lf.size += int64(len(chunk))
+				break
+			}
+			lf.size += int64(i + 1)
+			chunk = chunk[i+1:]
+			lf.lineStart = true
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !progress(lf.size) {
+			return errCancelled
+		}
+	}
+}
+
+func (lf *lazyFile) numRows() int {
+	return len(lf.offsets)
+}
+
+// Return row i, reading it from disk unless cached.
+func (lf *lazyFile) row(i, tabStop int) *Row {
+	if row, ok := lf.cache[i]; ok {
+		return row
+	}
+	if len(lf.cache) >= LAZY_CACHE_ROWS {
+		lf.cache = make(map[int]*Row)
+	}
+	end := lf.size
+	if i+1 < len(lf.offsets) {
+		end = lf.offsets[i+1]
+	}
+	buf := make([]byte, end-lf.offsets[i])
+	// A failed read leaves the row empty rather than stopping the viewer.
+	n, _ := lf.f.ReadAt(buf, lf.offsets[i])
+	line := strings.TrimSuffix(string(buf[:n]), "\n")
+	line = strings.TrimSuffix(line, "\r")
+
+	row := &Row{chars: line}
+	row.update(tabStop)
+	// Rows are read out of order, so highlight each one on its own. That
+	// leaves out lines inside block comments.
+	row.highlight(ST_NONE, nil)
+	lf.cache[i] = row
+	return row
+}
+
+// Forget cached rows, e.g. after the tab stop changed.
+func (lf *lazyFile) flush() {
+	lf.cache = make(map[int]*Row)
+}
+
+func (lf *lazyFile) close() {
+	lf.f.Close()
+}
@@ -0,0 +1,59 @@
+package editor
+
+// Length of the leading whitespace of chars if it doesn't fit the buffer's
+// indentation style, 0 if it does. Indenting with spaces, any tab is out of
+// place. Indenting with tabs, so is a space before a tab, or spaces making
+// up a whole tab's width or more.
+func (ed *Editor) badIndent(chars string) int {
+	n := firstNonBlank(chars)
+	indent := chars[:n]
+	if ed.cfg.expandTabs {
+		for i := 0; i < n; i++ {
+			if indent[i] == '\t' {
+				return n
+			}
+		}
+		return 0
+	}
+	spaces := 0
+	for i := 0; i < n; i++ {
+		if indent[i] == ' ' {
+			spaces++
+			continue
+		}
+		if spaces > 0 {
+			return n
+		}
+	}
+	if spaces >= ed.cfg.tabStop {
+		return n
+	}
+	return 0
+}
+
+// Mark the leading whitespace of row in hl when it doesn't fit the style.
+func (ed *Editor) markBadIndent(row *Row) {
+	n := ed.badIndent(row.chars)
+	for j := 0; j < row.cxToRx(n, ed.cfg.tabStop); j++ {
+		if row.hl[j] == HL_NORMAL {
+			row.hl[j] = HL_BAD_INDENT
+		}
+	}
+}
+
+// Move the cursor to the next row whose indentation doesn't fit the style,
+// wrapping around the buffer.
+func (ed *Editor) jumpBadIndent() {
+	n := ed.numRows()
+	for i := 1; i <= n; i++ {
+		if ed.scanCancelled(i) {
+			return
+		}
+		y := (ed.cy + i) % n
+		if ed.badIndent(ed.row(y).chars) > 0 {
+			ed.cy, ed.cx = y, 0
+			return
+		}
+	}
+	ed.setStatus("No inconsistent indentation")
+}
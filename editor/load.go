@@ -0,0 +1,65 @@
+package editor
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Files at least this large show their progress while loading.
+const LOAD_PROGRESS_BYTES = 16 << 20
+
+// Bytes read between progress updates.
+const LOAD_PROGRESS_STEP = 1 << 20
+
+// Show how far loading a file of size bytes got and draw the rows read so
+// far. Return false when Escape was typed to stop loading.
+func (ed *Editor) loadProgress(done, size int64) bool {
+	if size < LOAD_PROGRESS_BYTES {
+		return true
+	}
+	ed.setStatus("Loading... %d%%", done*100/size)
+	ed.refresh()
+	return !ed.keys.cancelled()
+}
+
+// Reads a file being loaded, reporting progress every LOAD_PROGRESS_STEP
+// bytes. Reading fails with errCancelled once loading is stopped.
+type progressReader struct {
+	ed         *Editor
+	r          io.Reader
+	done, size int64
+	next       int64
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	if pr.done >= pr.next {
+		if !pr.ed.loadProgress(pr.done, pr.size) {
+			return 0, errCancelled
+		}
+		pr.next = pr.done + LOAD_PROGRESS_STEP
+	}
+	n, err := pr.r.Read(p)
+	pr.done += int64(n)
+	return n, err
+}
+
+// Ask before opening a file bigger than confirmsize, which takes a while
+// to read in. Return whether to open it, and whether read-only, reading
+// from disk as it's viewed like a file over largefile.
+func (ed *Editor) confirmSize(filename string) (open, lazy bool) {
+	fi, err := os.Stat(filename)
+	if err != nil || ed.cfg.confirmSize == 0 || fi.Size() <= ed.cfg.confirmSize {
+		return true, false
+	}
+	question := fmt.Sprintf("%s is %d MB, open it? (y/n, r for read-only) ", filename, fi.Size()>>20)
+	answer, ok := ed.prompt(question, nil)
+	switch {
+	case ok && (answer == "y" || answer == "yes"):
+		return true, false
+	case ok && answer == "r":
+		return true, true
+	}
+	ed.fail("Not opened")
+	return false, false
+}
@@ -0,0 +1,56 @@
+package editor
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfirmSize(t *testing.T) {
+	small := writeTemp(t, "small.txt", "small\n")
+	big := filepath.Join(filepath.Dir(small), "big.txt")
+	if err := ioutil.WriteFile(big, []byte(strings.Repeat("big line\n", 100)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		answer   string
+		filename string
+		edited   bool
+	}{
+		{"n", small, false},
+		{"", small, false},
+		{"y", big, true},
+		{"r", big, false},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.confirmSize = 100
+		ed, _ := runKeys(t, cfg, small, ":e "+big+"\r"+tt.answer+"\r")
+		if ed.filename != tt.filename {
+			t.Errorf("answer %q: editing %s, want %s", tt.answer, ed.filename, tt.filename)
+			continue
+		}
+		if tt.filename == small {
+			if ed.statusmsg != "Not opened" {
+				t.Errorf("answer %q: status %q, want Not opened", tt.answer, ed.statusmsg)
+			}
+			continue
+		}
+		if edited := ed.checkWritable(); edited != tt.edited {
+			t.Errorf("answer %q: can be edited %v, want %v", tt.answer, edited, tt.edited)
+		}
+	}
+
+	// Started on a big file, a no means not to start at all.
+	cfg := DefaultConfig()
+	cfg.confirmSize = 100
+	if _, _, err := RunScript(cfg, big, strings.NewReader("n\r"), ioutil.Discard); err == nil {
+		t.Error("started on a big file the answer was no to")
+	}
+	// Under the size it opens without asking, and the n is typed.
+	ed, _ := runKeys(t, DefaultConfig(), big, insertKey+"n")
+	if ed.filename != big || !strings.HasPrefix(ed.Contents(), "nbig line") {
+		t.Errorf("editing %s, contents start %q", ed.filename, ed.Contents()[:10])
+	}
+}
@@ -0,0 +1,89 @@
+package editor
+
+import (
+	"bytes"
+	"time"
+)
+
+// How long a status message stays up.
+const MSG_TIMEOUT = 5 * time.Second
+
+// With longmsg=scroll, how long a message too long for the bar stands
+// still before it scrolls, and how often it moves on a column.
+const MARQUEE_PAUSE = time.Second
+const MARQUEE_STEP = 150 * time.Millisecond
+
+// Space between the end of a scrolling message and its start coming round.
+const MARQUEE_GAP = "   "
+
+// The status message as it is shown now, "" once it timed out. A message
+// scrolled through stays up until it came round once.
+func (ed *Editor) visibleMessage() string {
+	shown := MSG_TIMEOUT
+	if ed.scrollingMessage() {
+		if round := MARQUEE_PAUSE + time.Duration(len(ed.statusmsg)+len(MARQUEE_GAP))*MARQUEE_STEP; round > shown {
+			shown = round
+		}
+	}
+	if time.Since(ed.statusmsgTime) >= shown {
+		return ""
+	}
+	return ed.statusmsg
+}
+
+// Whether the message is scrolled through rather than shown whole. A
+// prompt isn't, what is typed must stay in view.
+func (ed *Editor) scrollingMessage() bool {
+	return ed.cfg.longMsg == "scroll" && len(ed.statusmsg) > ed.width && !ed.prompting
+}
+
+// Lines the message area takes: one, or with longmsg=wrap as many as the
+// message needs, up to msglines and leaving most of the screen to text.
+func (ed *Editor) msgRows() int {
+	if ed.cfg.longMsg != "wrap" || ed.width <= 0 {
+		return 1
+	}
+	limit := clamp((ed.height-2)/2, 1, ed.cfg.msgLines)
+	return clamp((len(ed.visibleMessage())+ed.width-1)/ed.width, 1, limit)
+}
+
+// Draw line y of the message area, see longmsg for what becomes of a
+// message longer than the screen is wide.
+func (ed *Editor) drawMessageBar(ab *bytes.Buffer, y int) {
+	ab.WriteString("\x1b[K")
+	msg := ed.visibleMessage()
+	if msg == "" && y == 0 && ed.cfg.keyHints && !ed.prompting {
+		// Already cut to fit.
+		ab.WriteString(ed.hintText(ed.keyHints(), ed.width))
+		return
+	}
+	if len(msg) > ed.width {
+		switch {
+		case ed.cfg.longMsg == "wrap":
+			msg = msg[clamp(y*ed.width, 0, len(msg)):]
+		case ed.cfg.longMsg == "scroll" && ed.prompting:
+			// The end, where the typing goes.
+			msg = msg[len(msg)-ed.width:]
+		case ed.cfg.longMsg == "scroll":
+			msg = ed.marquee(msg)
+		}
+	}
+	ab.WriteString(msg[:clamp(len(msg), 0, ed.width)])
+}
+
+// The part of msg to show in the bar now, with the message scrolling left
+// after a pause and its start following it round. Nothing else may redraw
+// the screen, so the next step is asked for here.
+func (ed *Editor) marquee(msg string) string {
+	loop := msg + MARQUEE_GAP
+	off := 0
+	if elapsed := time.Since(ed.statusmsgTime) - MARQUEE_PAUSE; elapsed > 0 {
+		off = int(elapsed/MARQUEE_STEP) % len(loop)
+	}
+	// Frames drawn for keys in between don't add to the steps asked for.
+	if now := time.Now(); !now.Before(ed.marqueeWake) {
+		ed.marqueeWake = now.Add(MARQUEE_STEP)
+		time.AfterFunc(MARQUEE_STEP, ed.keys.wakeUp)
+	}
+	return (loop + msg)[off:]
+}
@@ -0,0 +1,76 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Lines looked at for each row of the minimap at most, so a huge file
+// doesn't cost a read of all of it every frame.
+const MINIMAP_SAMPLE = 8
+
+// Characters for how full a part of the minimap is, emptiest first.
+var minimapShades = []string{" ", "░", "▒", "▓", "█"}
+
+// Columns the minimap takes at the right edge, 0 when it is off or there
+// is no text to map.
+func (ed *Editor) minimapWidth() int {
+	if !ed.cfg.minimap || ed.browser != nil || ed.hex != nil {
+		return 0
+	}
+	return ed.cfg.minimapWidth
+}
+
+// Draw the minimap on screen line y: the rows of the buffer the line stands
+// for, shaded by how much text they have in each column's share of
+// maxwidth, with the lines on screen marked. A file shorter than the screen
+// gets a line per row.
+func (ed *Editor) drawMinimap(ab *bytes.Buffer, y int) {
+	if ed.mapWidth == 0 {
+		return
+	}
+	fmt.Fprintf(ab, "\x1b[%d;%dH", y+1, ed.width-ed.mapWidth+1)
+	n := ed.numRows()
+	from, to := y, y+1
+	if n > ed.screenrows {
+		from, to = y*n/ed.screenrows, (y+1)*n/ed.screenrows
+	}
+	to = clamp(to, 0, n)
+	if from >= to {
+		ab.WriteString(strings.Repeat(" ", ed.mapWidth))
+		return
+	}
+	// Characters that aren't blank on the sampled rows, in each column's
+	// share of the text width.
+	share := clamp(ed.cfg.maxWidth/ed.mapWidth, 1, ed.cfg.maxWidth)
+	filled := make([]int, ed.mapWidth)
+	step := clamp((to-from)/MINIMAP_SAMPLE, 1, to-from)
+	samples := 0
+	for i := from; i < to; i += step {
+		render := ed.row(i).render
+		for x := 0; x < len(render) && x/share < ed.mapWidth; x++ {
+			if render[x] != ' ' {
+				filled[x/share]++
+			}
+		}
+		samples++
+	}
+	class := HL_MINIMAP
+	if to > ed.rowoff && from < ed.rowoff+ed.screenrows {
+		class = HL_MINIMAP_VIEW
+	}
+	if color := ed.hlColor(class); color != "" {
+		ab.WriteString("\x1b[" + color + "m")
+	}
+	last := len(minimapShades) - 1
+	for _, f := range filled {
+		shade := clamp(f*last/(samples*share), 0, last)
+		if f > 0 && shade == 0 {
+			// Any text at all shows.
+			shade = 1
+		}
+		ab.WriteString(minimapShades[shade])
+	}
+	ab.WriteString("\x1b[m")
+}
@@ -0,0 +1,89 @@
+package editor
+
+import "strings"
+
+// Rows at the start and at the end of a file searched for modelines.
+const MODELINE_ROWS = 5
+
+// Apply the settings of modelines near the start or end of the file, e.g.
+// "# vim: ts=4 et" or "// exa: tabstop=4 expandtab=on filetype=go". They
+// override what detectIndent guessed. Unknown or malformed settings are
+// skipped, a file never gets to stop the editor from opening it.
+func (ed *Editor) applyModelines() {
+	n := ed.numRows()
+	changed := false
+	for i := 0; i < n; i++ {
+		if i == MODELINE_ROWS && n-MODELINE_ROWS > i {
+			i = n - MODELINE_ROWS
+		}
+		for _, opt := range modelineOptions(ed.row(i).chars) {
+			if ed.applyModelineOption(opt) {
+				changed = true
+			}
+		}
+	}
+	if changed {
+		ed.updateRows()
+	}
+}
+
+// Split the options out of a modeline, nil if line isn't one. Both the
+// "vim: ts=4 et" form and the "vim: set ts=4 et:" form are understood.
+func modelineOptions(line string) []string {
+	idx := -1
+	for _, tag := range []string{"exa:", "vim:", "vi:", "ex:"} {
+		if i := strings.Index(line, tag); i >= 0 {
+			// The tag must start a word, or e.g. "index:" would match.
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '\t' {
+				idx = i + len(tag)
+				break
+			}
+		}
+	}
+	if idx < 0 {
+		return nil
+	}
+	rest := strings.TrimSpace(line[idx:])
+	if strings.HasPrefix(rest, "set ") || strings.HasPrefix(rest, "se ") {
+		// Options end at the next colon, anything after is text.
+		rest = rest[strings.IndexByte(rest, ' ')+1:]
+		if end := strings.IndexByte(rest, ':'); end >= 0 {
+			rest = rest[:end]
+		}
+		return strings.Fields(rest)
+	}
+	return strings.FieldsFunc(rest, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ':'
+	})
+}
+
+// Apply one "name=value" or "name" modeline option. Return whether it was
+// understood and applied.
+func (ed *Editor) applyModelineOption(opt string) bool {
+	name, value := opt, ""
+	if i := strings.IndexByte(opt, '='); i >= 0 {
+		name, value = opt[:i], opt[i+1:]
+	}
+	switch name {
+	case "tabstop", "ts":
+		return ed.cfg.set("tabstop="+value) == nil
+	case "shiftwidth", "sw":
+		return ed.cfg.set("shiftwidth="+value) == nil
+	case "expandtab", "et":
+		if value == "" {
+			value = "on"
+		}
+		return ed.cfg.set("expandtab="+value) == nil
+	case "noexpandtab", "noet":
+		ed.cfg.expandTabs = false
+		return true
+	case "filetype", "ft":
+		for i := range syntaxes {
+			if syntaxes[i].filetype == value {
+				ed.syntax = &syntaxes[i]
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package editor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestModelineOptions(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"# vim: ts=4 et", []string{"ts=4", "et"}},
+		{"/* vim: set sw=2 noet: */", []string{"sw=2", "noet"}},
+		{"// exa: tabstop=4:expandtab=on", []string{"tabstop=4", "expandtab=on"}},
+		{"see index: 3", nil},
+		{"plain text", nil},
+	}
+	for _, tt := range tests {
+		if got := modelineOptions(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("modelineOptions(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+// Open text as a file and return the editor with its modelines applied.
+func openModeline(t *testing.T, text string) *Editor {
+	t.Helper()
+	ed := New(DefaultConfig(), strings.NewReader(""), nil)
+	if err := ed.openArg(writeTemp(t, "a.txt", text)); err != nil {
+		t.Fatal(err)
+	}
+	return ed
+}
+
+func TestModelineWellFormed(t *testing.T) {
+	ed := openModeline(t, "x\n\ty\n# vim: ts=4 sw=2 et ft=python\n")
+	if ed.cfg.tabStop != 4 || ed.cfg.shiftWidth != 2 || !ed.cfg.expandTabs {
+		t.Errorf("tabstop %d shiftwidth %d expandtab %v, want 4 2 true", ed.cfg.tabStop, ed.cfg.shiftWidth, ed.cfg.expandTabs)
+	}
+	if ed.syntax == nil || ed.syntax.filetype != "python" {
+		t.Errorf("syntax %+v, want python", ed.syntax)
+	}
+	if r := ed.rows[1].render; r != "    y" {
+		t.Errorf("tab drawn as %q, want 4 wide", r)
+	}
+}
+
+func TestModelineMalformed(t *testing.T) {
+	// Bad values and unknown names are skipped, the rest still applies.
+	ed := openModeline(t, "# vim: ts=lots sw=3 bogus=1 ft=nosuch\n")
+	if ed.cfg.tabStop != 8 || ed.cfg.shiftWidth != 3 {
+		t.Errorf("tabstop %d shiftwidth %d, want 8 3", ed.cfg.tabStop, ed.cfg.shiftWidth)
+	}
+	if ed.syntax == nil || ed.syntax.filetype != "text" {
+		t.Errorf("syntax %+v, want text from the extension", ed.syntax)
+	}
+}
+
+func TestModelineHugeTabStop(t *testing.T) {
+	ed := openModeline(t, "\tx\n# vim: ts=999999999 sw=999999999\n")
+	if ed.cfg.tabStop != 8 || ed.cfg.shiftWidth != 0 {
+		t.Errorf("tabstop %d shiftwidth %d, want the huge ones refused", ed.cfg.tabStop, ed.cfg.shiftWidth)
+	}
+	if r := ed.rows[0].render; len(r) != 9 {
+		t.Errorf("tab drawn %d wide", len(r)-1)
+	}
+}
@@ -0,0 +1,137 @@
+package editor
+
+import (
+	"encoding/base64"
+	"io"
+	"strings"
+	"time"
+)
+
+// Longest OSC 52 payload sent, in bytes of base64, xterm's default limit.
+// Terminals drop or cut off longer ones, so bigger copies stay in exa.
+const OSC52_MAX = 100000
+
+// The sequence goes out in writes of at most this many bytes, so it doesn't
+// overflow the input buffer of a terminal or multiplexer in one go.
+const OSC52_CHUNK = 4096
+
+// Put text on the terminal's clipboard with OSC 52, for terminals that have
+// it, also over ssh. Return false if it's too big to send.
+func (ed *Editor) copyToClipboard(text string) bool {
+	data := base64.StdEncoding.EncodeToString([]byte(text))
+	if len(data) > OSC52_MAX {
+		return false
+	}
+	seq := "\x1b]52;c;" + data + "\a"
+	for len(seq) > 0 {
+		n := len(seq)
+		if n > OSC52_CHUNK {
+			n = OSC52_CHUNK
+		}
+		io.WriteString(ed.out, seq[:n])
+		seq = seq[n:]
+	}
+	return true
+}
+
+// How long paste waits for the terminal to answer an OSC 52 query. Many
+// don't, some only when allowed to, and then the register is pasted.
+const OSC52_TIMEOUT = 500 * time.Millisecond
+
+// An answer coming this long after the query is no longer taken for one.
+const OSC52_LATE = 10 * time.Second
+
+// Paste, with osc52paste on the terminal's clipboard. What exa put there
+// itself goes in from the register, as it was copied, lines as lines. No
+// answer in time pastes the register too.
+func (ed *Editor) pasteClipboard() {
+	reg, yank := ed.lastYank(), 0
+	if ed.cfg.osc52Paste && ed.checkWritable() {
+		io.WriteString(ed.out, "\x1b]52;c;?\a")
+		ed.keys.queried = time.Now()
+		if text, ok := ed.keys.readClipboard(OSC52_TIMEOUT); ok && text != "" && text != reg.clipboardText() {
+			reg, yank = register{text: text}, -1
+		}
+	}
+	ed.put(reg, yank)
+}
+
+// Wait up to timeout for the terminal's answer to an OSC 52 query, keeping
+// the keys typed meanwhile for after. ok is false when none came or it
+// didn't decode.
+func (kr *keyReader) readClipboard(timeout time.Duration) (text string, ok bool) {
+	var kept []byte
+	defer func() {
+		kr.pending = append(kept, kr.pending...)
+	}()
+	deadline := time.Now().Add(timeout)
+	for {
+		left := time.Until(deadline)
+		if left <= 0 {
+			return "", false
+		}
+		b, ok := kr.next(left)
+		if !ok {
+			return "", false
+		}
+		if b != 0x1b {
+			kept = append(kept, b)
+			continue
+		}
+		c, ok := kr.next(ESC_TIMEOUT)
+		if !ok {
+			kept = append(kept, b)
+			continue
+		}
+		if c != ']' {
+			kept = append(kept, b, c)
+			continue
+		}
+		body, done := kr.readOSC()
+		if !done {
+			return "", false
+		}
+		if text, ok := parseOSC52(body); ok {
+			kr.queried = time.Time{}
+			return text, true
+		}
+		// Some other answer, not wanted.
+	}
+}
+
+// The rest of an OSC sequence, the <esc>] read already, up to the BEL or
+// <esc>\ ending it. done is false if it was cut short.
+func (kr *keyReader) readOSC() (body string, done bool) {
+	var buf []byte
+	for {
+		b, ok := kr.next(PASTE_TIMEOUT)
+		if !ok {
+			return string(buf), false
+		}
+		switch b {
+		case '\a':
+			return string(buf), true
+		case 0x1b:
+			b, ok = kr.next(ESC_TIMEOUT)
+			return string(buf), ok && b == '\\'
+		}
+		buf = append(buf, b)
+	}
+}
+
+// The clipboard text in the body of an OSC 52 answer, "52;c;<base64>".
+func parseOSC52(body string) (string, bool) {
+	if !strings.HasPrefix(body, "52;") {
+		return "", false
+	}
+	body = body[len("52;"):]
+	i := strings.IndexByte(body, ';')
+	if i < 0 {
+		return "", false
+	}
+	data, err := base64.StdEncoding.DecodeString(body[i+1:])
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
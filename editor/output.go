@@ -0,0 +1,197 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Output of a command, e.g. a build or test run, shown in a pane below the
+// buffer. Lines naming a file location can be jumped to.
+type outputPane struct {
+	title string
+	lines []string
+	// Line under the cursor and the first one on screen.
+	cursor, top int
+	// Keys go to the pane rather than the buffer.
+	focused bool
+	// The lines are command output whose file locations are diagnostics.
+	diagnostics bool
+	// The lines are a diff, colored by what each does.
+	diff bool
+}
+
+// A "file:line" or "file:line:col" location at the start of a line of
+// compiler or grep output.
+var locationPattern = regexp.MustCompile(`^([^:\s][^:]*):(\d+)(?::(\d+))?`)
+
+// Rows the pane takes, its title bar included, with at least half the text
+// rows left to the buffer.
+func (ed *Editor) paneRows() int {
+	if ed.pane == nil {
+		return 0
+	}
+	return clamp(ed.cfg.paneHeight+1, 0, (ed.height-2)/2)
+}
+
+// Open the pane on lines, focused so they can be walked through.
+func (ed *Editor) showPane(title string, lines []string) {
+	ed.pane = &outputPane{title: title, lines: lines, focused: true}
+	ed.updateSize()
+}
+
+func (ed *Editor) closePane() {
+	ed.pane = nil
+	ed.updateSize()
+}
+
+// Run a shell command and show what it printed in the pane. A failing
+// command still shows its output, that is usually what it's run for.
+func (ed *Editor) runToPane(command string) {
+	out, err := runCommand(command, ed.keys.cancelled)
+	if err == errCancelled {
+		ed.setStatus("Cancelled")
+		return
+	}
+	title := command
+	if err != nil {
+		title = fmt.Sprintf("%s (%v)", command, err)
+	}
+	ed.showPane(title, strings.Split(strings.TrimSuffix(out, "\n"), "\n"))
+	ed.pane.diagnostics = true
+	if n := ed.applyDiagnostics(ed.pane.lines); n > 0 {
+		ed.setStatus("%d lines with diagnostics", n)
+	}
+}
+
+// Move keys between the pane and the buffer.
+func (ed *Editor) togglePaneFocus() {
+	if ed.pane == nil {
+		ed.setStatus("No output pane")
+		return
+	}
+	ed.pane.focused = !ed.pane.focused
+}
+
+// Handle a key in the focused pane. Return false to quit.
+func (ed *Editor) paneKeyPress(ch EdKey) bool {
+	p := ed.pane
+	rows := ed.paneRows() - 1
+	switch ch {
+	case ARW_UP:
+		p.cursor--
+	case ARW_DOWN:
+		p.cursor++
+	case PG_UP:
+		p.cursor -= rows
+	case PG_DOWN:
+		p.cursor += rows
+	case HOME_KEY:
+		p.cursor = 0
+	case END_KEY:
+		p.cursor = len(p.lines) - 1
+	case '\r':
+		// Keys can come in faster than the frames that keep the cursor
+		// on a line, see paneScroll.
+		if p.cursor = clamp(p.cursor, 0, len(p.lines)-1); p.cursor >= 0 {
+			ed.jumpToLocation(p.lines[p.cursor])
+		}
+	case ':':
+		return ed.commandMode()
+	case 0x1b, 'q':
+		ed.closePane()
+	default:
+		switch ed.cfg.keymap[ch] {
+		case "quit":
+			return ed.quit()
+		case "pane":
+			p.focused = false
+		}
+	}
+	return true
+}
+
+// Open the location a line of output starts with and give the keys back
+// to the buffer.
+func (ed *Editor) jumpToLocation(line string) {
+	m := locationPattern.FindStringSubmatch(line)
+	if m == nil {
+		ed.setStatus("No file location on this line")
+		return
+	}
+	row, _ := strconv.Atoi(m[2])
+	col, _ := strconv.Atoi(m[3])
+	if ed.sameFile(m[1]) {
+		ed.jumpTo(row, col)
+	} else if !ed.edit(m[1], row, col) {
+		return
+	} else if ed.pane.diagnostics {
+		ed.applyDiagnostics(ed.pane.lines)
+	}
+	ed.pane.focused = false
+}
+
+// Keep the pane cursor on a line and on screen.
+func (ed *Editor) paneScroll() {
+	p := ed.pane
+	// On a tiny screen the pane can have no room for lines at all.
+	rows := clamp(ed.paneRows()-1, 1, ed.height)
+	p.cursor = clamp(p.cursor, 0, len(p.lines)-1)
+	if p.cursor < p.top {
+		p.top = p.cursor
+	}
+	if p.cursor >= p.top+rows {
+		p.top = p.cursor - rows + 1
+	}
+}
+
+// Draw line y of the pane, the title bar first.
+func (ed *Editor) drawPaneRow(ab *bytes.Buffer, y int) {
+	p := ed.pane
+	if y == 0 {
+		title := fmt.Sprintf("%s - %d lines", p.title, len(p.lines))
+		title = title[:clamp(len(title), 0, ed.width)]
+		ab.WriteString("\x1b[7m" + title + strings.Repeat(" ", ed.width-len(title)) + "\x1b[m")
+		return
+	}
+	if i := p.top + y - 1; i < len(p.lines) {
+		line, style := p.lines[i], ""
+		if i == p.cursor {
+			style = "7"
+		} else if p.diff {
+			style = ed.hlColor(diffHighlight(line))
+		}
+		ed.drawText(ab, line, style)
+	}
+	ab.WriteString("\x1b[K")
+}
+
+// Draw a line of text from elsewhere, e.g. command output, as buffer rows
+// are drawn: tabs expanded and control characters in caret notation, so
+// none reach the terminal. Cut at the screen width, drawn in style, SGR
+// parameters or "" for none.
+func (ed *Editor) drawText(ab *bytes.Buffer, text, style string) {
+	row := Row{chars: text}
+	row.update(ed.cfg.tabStop)
+	render := row.render[:clamp(len(row.render), 0, ed.width)]
+	on := ""
+	if style != "" {
+		on = "\x1b[" + style + "m"
+	}
+	ab.WriteString(on)
+	ctrl, last := ed.hlColor(HL_CONTROL), 0
+	for _, at := range row.ctrl {
+		if at >= len(render) || ctrl == "" {
+			break
+		}
+		end := clamp(at+2, 0, len(render))
+		ab.WriteString(render[last:at] + "\x1b[" + ctrl + "m" + render[at:end] + "\x1b[m" + on)
+		last = end
+	}
+	ab.WriteString(render[last:])
+	if style != "" {
+		ab.WriteString("\x1b[m")
+	}
+}
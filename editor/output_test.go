@@ -0,0 +1,35 @@
+package editor
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestPaneEnterAfterKeyBurst(t *testing.T) {
+	// Up moves the pane cursor off the first line, and Enter comes before
+	// a frame could put it back.
+	ed, _ := runKeys(t, DefaultConfig(), "", ":run echo hi\r\x1b[A\r")
+	if ed.pane == nil || ed.pane.cursor != 0 {
+		t.Fatalf("pane %+v, want it open with the cursor on the first line", ed.pane)
+	}
+}
+
+func TestDrawTextEscapesControls(t *testing.T) {
+	ed := New(DefaultConfig(), strings.NewReader(""), ioutil.Discard)
+	ed.width = 12
+	var b bytes.Buffer
+	ed.drawText(&b, "a\x1b[2J\tb\x07 and more", "")
+	want := "a\x1b[7m^[\x1b[m[2J  b\x1b[7m^G\x1b[m "
+	if got := b.String(); got != want {
+		t.Errorf("drawn %q, want %q", got, want)
+	}
+
+	b.Reset()
+	ed.drawText(&b, "x\x1by", "7")
+	want = "\x1b[7mx\x1b[7m^[\x1b[m\x1b[7my\x1b[m"
+	if got := b.String(); got != want {
+		t.Errorf("drawn %q in style, want %q", got, want)
+	}
+}
@@ -0,0 +1,98 @@
+package editor
+
+// Rows looked through for the partner of a block keyword, so a keyword
+// without one doesn't cost a scan of the whole file on every key.
+const PAIR_SCAN_ROWS = 1000
+
+// The chars start to end of a row.
+type wordSpan struct {
+	row, start, end int
+}
+
+// Whether c can be part of a block keyword.
+func isKeywordChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// Ranges of the words in chars before any comment.
+func (ed *Editor) keywordSpans(chars string) [][2]int {
+	end := len(chars)
+	if c := ed.syntax.commentStart(chars); c >= 0 {
+		end = c
+	}
+	var spans [][2]int
+	for i := 0; i < end; i++ {
+		if !isKeywordChar(chars[i]) {
+			continue
+		}
+		j := i
+		for j < end && isKeywordChar(chars[j]) {
+			j++
+		}
+		spans = append(spans, [2]int{i, j})
+		i = j
+	}
+	return spans
+}
+
+// The block keyword under the cursor and its partner, counting nested
+// blocks on the way, in filetypes with block pairs and with matchpairs on.
+// None if the cursor isn't on one or no partner is found nearby. Words in
+// comments don't count, quotes aren't understood.
+func (ed *Editor) findBlockPair() []wordSpan {
+	if !ed.cfg.matchPairs || ed.syntax == nil || len(ed.syntax.blockPairs) == 0 || ed.numRows() == 0 {
+		return nil
+	}
+	pairs := ed.syntax.blockPairs
+	chars := ed.row(ed.cy).chars
+	var at wordSpan
+	for _, s := range ed.keywordSpans(chars) {
+		if ed.cx >= s[0] && ed.cx < s[1] {
+			at = wordSpan{ed.cy, s[0], s[1]}
+		}
+	}
+	word := chars[at.start:at.end]
+	closer, opens := pairs[word]
+	closes := false
+	for _, c := range pairs {
+		closes = closes || c == word
+	}
+	if word == "" || (!opens && !closes) {
+		return nil
+	}
+	depth := 0
+	// Openers go forward from the keyword to their closer, closers back.
+	dir := 1
+	if !opens {
+		closer, dir = word, -1
+	}
+	for i := 0; i < PAIR_SCAN_ROWS && at.row+dir*i >= 0 && at.row+dir*i < ed.numRows(); i++ {
+		y := at.row + dir*i
+		row := ed.row(y).chars
+		spans := ed.keywordSpans(row)
+		for n := range spans {
+			s := spans[n]
+			if dir < 0 {
+				s = spans[len(spans)-1-n]
+			}
+			// On the keyword's own row, only what comes after it (or
+			// before, going back) and the keyword itself count.
+			if i == 0 && ((dir > 0 && s[0] < at.start) || (dir < 0 && s[0] > at.start)) {
+				continue
+			}
+			w := row[s[0]:s[1]]
+			switch {
+			case pairs[w] == closer:
+				depth += dir
+			case w == closer:
+				depth -= dir
+			default:
+				continue
+			}
+			if depth == 0 {
+				return []wordSpan{at, {y, s[0], s[1]}}
+			}
+		}
+	}
+	return nil
+}
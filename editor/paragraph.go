@@ -0,0 +1,30 @@
+package editor
+
+// Move the cursor to the next blank row below (dir 1) or above (dir -1) the
+// paragraph it is in, like } and { in vi. A run of blank rows is one
+// boundary: from a blank row the move goes past the paragraph after the
+// run. Without a blank row to go to, the cursor goes to the last or first
+// row.
+func (ed *Editor) jumpParagraph(dir int) {
+	n := ed.numRows()
+	if n == 0 {
+		return
+	}
+	y := ed.cy
+	for i := 0; y+dir >= 0 && y+dir < n && ed.row(y).blank(); i++ {
+		if ed.scanCancelled(i) {
+			return
+		}
+		y += dir
+	}
+	for i := 0; y+dir >= 0 && y+dir < n; i++ {
+		if ed.scanCancelled(i) {
+			return
+		}
+		y += dir
+		if ed.row(y).blank() {
+			break
+		}
+	}
+	ed.cy, ed.cx = y, 0
+}
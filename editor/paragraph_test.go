@@ -0,0 +1,43 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJumpParagraph(t *testing.T) {
+	// Rows: 0 a, 1 b, 2 blank, 3 c, 4-6 blank, 7 d, 8 e.
+	path := writeTemp(t, "a.txt", "a\nb\n\nc\n\n\n\nd\ne\n")
+	last, down5 := strings.Repeat("\x1b[B", 8), strings.Repeat("\x1b[B", 5)
+	tests := []struct {
+		keys string
+		want int
+	}{
+		{"}", 2},
+		{"}}", 4},
+		{"}}}", 8},
+		{"}}}}", 8},
+		{last + "{", 6},
+		{last + "{{", 2},
+		{last + "{{{", 0},
+		{last + "{{{{", 0},
+		// From inside the run of blanks, on to the next boundary.
+		{down5 + "}", 8},
+		{down5 + "{", 2},
+	}
+	for _, tt := range tests {
+		ed, _ := runKeys(t, DefaultConfig(), path, tt.keys)
+		if ed.cy != tt.want || ed.cx != 0 {
+			t.Errorf("%q: cursor at %d,%d, want %d,0", tt.keys, ed.cy, ed.cx, tt.want)
+		}
+	}
+
+	// Paragraphs longer than the screen scroll the blank row into view.
+	long := strings.Repeat(strings.Repeat("text\n", 30)+"\n", 3)
+	path = writeTemp(t, "long.txt", long)
+	ed, _ := runKeys(t, DefaultConfig(), path, "}}")
+	ed.scroll()
+	if ed.cy != 61 || ed.cy < ed.rowoff || ed.cy >= ed.rowoff+ed.screenrows {
+		t.Errorf("cursor on row %d, showing rows %d to %d", ed.cy, ed.rowoff, ed.rowoff+ed.screenrows-1)
+	}
+}
@@ -0,0 +1,158 @@
+package editor
+
+import (
+	"strings"
+	"time"
+)
+
+// Ask the terminal to mark pasted text between <esc>[200~ and <esc>[201~,
+// and to stop again.
+const (
+	BRACKETED_PASTE_ON  = "\x1b[?2004h"
+	BRACKETED_PASTE_OFF = "\x1b[?2004l"
+)
+
+// How long a paste may pause before what came of it is taken as all of it.
+const PASTE_TIMEOUT = time.Second
+
+// Read pasted text up to the <esc>[201~ ending it, the <esc>[200~ read
+// already, and keep it for PASTE to be handled with.
+func (kr *keyReader) readPaste() EdKey {
+	const end = "\x1b[201~"
+	var buf []byte
+	for {
+		b, ok := kr.next(PASTE_TIMEOUT)
+		if !ok {
+			break
+		}
+		buf = append(buf, b)
+		if len(buf) >= len(end) && string(buf[len(buf)-len(end):]) == end {
+			buf = buf[:len(buf)-len(end)]
+			break
+		}
+	}
+	kr.pasted = string(buf)
+	return PASTE
+}
+
+// Insert pasted text at the cursor as it came, or with pastereindent
+// indented to fit where it goes.
+func (ed *Editor) paste(text string) {
+	if !ed.checkWritable() || text == "" {
+		return
+	}
+	if ed.numRows() == 0 {
+		ed.insertRow(0, "")
+	}
+	lines := strings.Split(strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(text), "\n")
+	if ed.cfg.pasteReindent {
+		lines = ed.reindentPaste(lines)
+	}
+	chars := ed.rows[ed.cy].chars
+	before, after := chars[:ed.cx], chars[ed.cx:]
+	last := len(lines) - 1
+	if last == 0 {
+		ed.rowSetChars(ed.cy, before+lines[0]+after)
+		ed.cx += len(lines[0])
+		return
+	}
+	rest := append(append([]string{}, lines[1:last]...), lines[last]+after)
+	ed.rowSetChars(ed.cy, before+lines[0])
+	ed.insertRows(ed.cy+1, rest)
+	ed.cy += last
+	ed.cx = len(lines[last])
+}
+
+// Indent pasted lines for the cursor line, keeping how many levels deeper
+// than each other they are. The indentation they come with is the least of
+// the lines, the first one only counting when it starts with whitespace, as
+// it would when not copied from the middle of a line, and a level is the
+// step all the deeper ones are a multiple of. The first line goes in at the
+// cursor, so it only loses its own indentation when the cursor is in the
+// leading whitespace of its line. The new indentation is in the buffer's
+// style, tabs or spaces, a level being indentWidth.
+func (ed *Editor) reindentPaste(lines []string) []string {
+	if len(lines) < 2 {
+		return lines
+	}
+	tabStop := ed.cfg.tabStop
+	base := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" || (i == 0 && firstNonBlank(line) == 0) {
+			continue
+		}
+		if cols := indentColumns(line, tabStop); base < 0 || cols < base {
+			base = cols
+		}
+	}
+	if base < 0 {
+		return lines
+	}
+	step := 0
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" || (i == 0 && firstNonBlank(line) == 0) {
+			continue
+		}
+		step = gcd(step, indentColumns(line, tabStop)-base)
+	}
+	// Columns of the new indentation for cols of the pasted.
+	levels := func(cols int) int {
+		if step == 0 || cols < base {
+			return 0
+		}
+		return (cols - base) / step * ed.indentWidth()
+	}
+	chars := ed.rows[ed.cy].chars
+	indent := chars[:firstNonBlank(chars)]
+	if ed.cx < len(indent) {
+		indent = indent[:ed.cx]
+	}
+	target := indentColumns(indent, tabStop)
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		text := line[firstNonBlank(line):]
+		switch {
+		case i == 0 && ed.cx <= len(indent):
+			out[i] = ed.indentTo(levels(indentColumns(line, tabStop))) + text
+		case i == 0:
+			out[i] = line
+		case text == "":
+			out[i] = ""
+		default:
+			out[i] = ed.indentTo(target+levels(indentColumns(line, tabStop))) + text
+		}
+	}
+	return out
+}
+
+// Greatest common divisor of a and b, a when b is 0.
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// Columns the leading whitespace of s takes.
+func indentColumns(s string, tabStop int) int {
+	cols := 0
+	for i := 0; i < firstNonBlank(s); i++ {
+		if s[i] == '\t' {
+			cols += tabStop - cols%tabStop
+		} else {
+			cols++
+		}
+	}
+	return cols
+}
+
+// Whitespace cols columns wide in the buffer's indentation style.
+func (ed *Editor) indentTo(cols int) string {
+	if cols <= 0 {
+		return ""
+	}
+	if ed.cfg.expandTabs {
+		return strings.Repeat(" ", cols)
+	}
+	return strings.Repeat("\t", cols/ed.cfg.tabStop) + strings.Repeat(" ", cols%ed.cfg.tabStop)
+}
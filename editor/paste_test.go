@@ -0,0 +1,44 @@
+package editor
+
+import "testing"
+
+func pasted(text string) string {
+	return "\x1b[200~" + text + "\x1b[201~"
+}
+
+func TestPaste(t *testing.T) {
+	tests := []struct {
+		name     string
+		reindent bool
+		expand   bool
+		text     string
+		// Keys to put the cursor in place first.
+		keys, paste, want string
+	}{
+		{"one line", false, false, "ab\n", "\x1b[C", "xy", "axyb\n"},
+		{"lines verbatim", false, false, "\tab\n", "\x1b[F", "\n  if x {\r\n    y()\n  }", "\tab\n  if x {\n    y()\n  }\n"},
+		{"keys in paste aren't commands", false, false, "\n", "", ":q\r", ":q\n\n"},
+		{"reindented to tabs", true, false, "\tf() {\n\t\t\n", "\x1b[B\x1b[F", "if x {\n    y()\n}", "\tf() {\n\t\tif x {\n\t\t\ty()\n\t\t}\n"},
+		{"reindented to spaces", true, true, "    f()\n    \n", "\x1b[B\x1b[F", "\tif x {\n\t\ty()\n\t}\n", "    f()\n    if x {\n        y()\n    }\n\n"},
+		{"first line mid-line", true, false, "\tx := \n", "\x1b[F", "f(\n\t\t\ta,\n\t\t)", "\tx := f(\n\t\ta,\n\t)\n"},
+		{"blank lines stay empty", true, false, "\t\n", "\x1b[F", "a\n  \n  b\n    c", "\ta\n\n\tb\n\t\tc\n"},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.pasteReindent = tt.reindent
+		ed, _ := runKeys(t, cfg, writeTemp(t, "a.txt", tt.text), tt.keys+pasted(tt.paste))
+		if tt.expand != ed.cfg.expandTabs {
+			t.Fatalf("%s: expandtab %v, want %v from the file", tt.name, ed.cfg.expandTabs, tt.expand)
+		}
+		if got := ed.Contents(); got != tt.want {
+			t.Errorf("%s: buffer %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPasteAtPrompt(t *testing.T) {
+	ed, _ := runKeys(t, DefaultConfig(), writeTemp(t, "a.txt", "a\n"), ":"+pasted("set tabstop=4\nmore")+"\r")
+	if ed.cfg.tabStop != 4 {
+		t.Errorf("tabstop %d, want the pasted command run", ed.cfg.tabStop)
+	}
+}
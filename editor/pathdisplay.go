@@ -0,0 +1,60 @@
+package editor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Values of the pathdisplay setting, in the order the pathdisplay action
+// cycles through them.
+var pathDisplays = []string{"given", "relative", "absolute", "base"}
+
+func validPathDisplay(value string) bool {
+	for _, v := range pathDisplays {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// A file name as the pathdisplay setting wants it shown. A file outside the
+// working directory is shown absolute rather than relative, a path climbing
+// out with ".." says less than the full one.
+func (cfg *Config) showPath(name string) string {
+	if cfg.pathDisplay == "given" {
+		return name
+	}
+	if cfg.pathDisplay == "base" {
+		return filepath.Base(name)
+	}
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return name
+	}
+	if cfg.pathDisplay == "absolute" {
+		return abs
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return abs
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return abs
+	}
+	return rel
+}
+
+// Switch to the next way of showing the file name.
+func (ed *Editor) cyclePathDisplay() {
+	next := pathDisplays[0]
+	for i, v := range pathDisplays {
+		if v == ed.cfg.pathDisplay && i+1 < len(pathDisplays) {
+			next = pathDisplays[i+1]
+		}
+	}
+	ed.cfg.pathDisplay = next
+	ed.setStatus("Showing %s file names: %s", next, ed.displayName())
+}
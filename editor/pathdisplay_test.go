@@ -0,0 +1,26 @@
+package editor
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestStatusName(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.pathDisplay = "base"
+	ed := New(cfg, strings.NewReader(""), nil)
+	ed.filename = filepath.Join("some", "dir", "a.txt")
+	ed.hex = &hexView{size: 3}
+	if left, _ := ed.hexStatus(); !strings.HasPrefix(left, "a.txt [hex]") {
+		t.Errorf("hex status %q, want the name as pathdisplay shows it", left)
+	}
+
+	// Cut by characters, not in the middle of one.
+	ed.filename = strings.Repeat("é", 30)
+	name := ed.statusName()
+	if !utf8.ValidString(name) || utf8.RuneCountInString(name) != 20 {
+		t.Errorf("status name %q, want the first 20 characters", name)
+	}
+}
@@ -0,0 +1,108 @@
+package editor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Files whose cursor position is remembered, the least recently left
+// ones are forgotten first.
+const POSITIONS_MAX = 500
+
+// Where the cursor was left in a file, and the size and modification time
+// (in nanoseconds) the file had then.
+type filePos struct {
+	path   string
+	cy, cx int
+	size   int64
+	mtime  int64
+}
+
+// Location of the position cache, next to the config file.
+func positionsPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "exa", "positions")
+}
+
+// Read the position cache, oldest entry first. The file holds one
+// "row col size mtime path" entry per line; lines that don't parse are
+// skipped.
+func loadPositions(path string) []filePos {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var positions []filePos
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var p filePos
+		fields := strings.SplitN(s.Text(), " ", 5)
+		if len(fields) != 5 {
+			continue
+		}
+		_, err := fmt.Sscan(strings.Join(fields[:4], " "), &p.cy, &p.cx, &p.size, &p.mtime)
+		if err != nil {
+			continue
+		}
+		p.path = fields[4]
+		positions = append(positions, p)
+	}
+	return positions
+}
+
+// Key of the file in the cache, its absolute path.
+func (ed *Editor) positionKey() string {
+	abs, err := filepath.Abs(ed.filename)
+	if err != nil {
+		return ""
+	}
+	return abs
+}
+
+// Remember where the cursor is in the file, for the next time it's opened.
+// Only files on disk are remembered, and failing to is not worth a message.
+func (ed *Editor) savePosition() {
+	cache := positionsPath()
+	key := ed.positionKey()
+	if !ed.cfg.restorePos || cache == "" || key == "" || ed.diskSize < 0 {
+		return
+	}
+	var b strings.Builder
+	positions := loadPositions(cache)
+	if len(positions) >= POSITIONS_MAX {
+		positions = positions[len(positions)-POSITIONS_MAX+1:]
+	}
+	for _, p := range positions {
+		if p.path != key {
+			fmt.Fprintf(&b, "%d %d %d %d %s\n", p.cy, p.cx, p.size, p.mtime, p.path)
+		}
+	}
+	fmt.Fprintf(&b, "%d %d %d %d %s\n", ed.cy, ed.cx, ed.diskSize, ed.diskTime.UnixNano(), key)
+	if os.MkdirAll(filepath.Dir(cache), 0700) == nil {
+		writeFileAtomic(cache, []byte(b.String()), true)
+	}
+}
+
+// Put the cursor back where it was left in the file, if it is remembered
+// and the file hasn't changed since.
+func (ed *Editor) restorePosition() {
+	cache := positionsPath()
+	key := ed.positionKey()
+	if !ed.cfg.restorePos || cache == "" || key == "" || ed.diskSize < 0 {
+		return
+	}
+	for _, p := range loadPositions(cache) {
+		if p.path == key && p.size == ed.diskSize && p.mtime == ed.diskTime.UnixNano() {
+			ed.jumpTo(p.cy+1, p.cx+1)
+			return
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package editor
+
+import "strings"
+
+// Text copied or cut, for paste to put back. Whole lines, linewise, go back
+// in as lines of their own, other text at the cursor.
+type register struct {
+	text     string
+	linewise bool
+}
+
+// The last paste, for yankpop to replace: the undo state and cursor it
+// left, and which of the yanks it put in, -1 for none of them.
+type lastPaste struct {
+	state, cy, cx int
+	yank          int
+}
+
+// Keep text for paste as the last yank, dropping the oldest past yankring,
+// and with osc52 on put it on the terminal's clipboard too, lines ending in
+// a newline there.
+func (ed *Editor) setRegister(text string, linewise bool) {
+	ed.yanks = append([]register{{text, linewise}}, ed.yanks...)
+	if len(ed.yanks) > ed.cfg.yankRing {
+		ed.yanks = ed.yanks[:ed.cfg.yankRing]
+	}
+	if ed.cfg.osc52 && !ed.copyToClipboard(ed.yanks[0].clipboardText()) {
+		ed.setStatus("Too big for the terminal's clipboard, only copied in exa")
+	}
+}
+
+// What was copied or cut last.
+func (ed *Editor) lastYank() register {
+	if len(ed.yanks) == 0 {
+		return register{}
+	}
+	return ed.yanks[0]
+}
+
+// The text as it goes on the terminal's clipboard.
+func (reg register) clipboardText() string {
+	if reg.linewise {
+		return reg.text + "\n"
+	}
+	return reg.text
+}
+
+// Copy the cursor line.
+func (ed *Editor) copyLine() {
+	if ed.numRows() == 0 {
+		ed.setStatus("Nothing to copy")
+		return
+	}
+	ed.setStatus("Copied a line")
+	ed.setRegister(ed.row(ed.cy).chars, true)
+}
+
+// Cut the cursor line, the last one left is only emptied.
+func (ed *Editor) cutLine() {
+	if !ed.checkWritable() || ed.numRows() == 0 {
+		return
+	}
+	chars := ed.rows[ed.cy].chars
+	if ed.numRows() == 1 {
+		ed.rowSetChars(0, "")
+	} else {
+		ed.delRow(ed.cy)
+	}
+	ed.cx = 0
+	ed.clampCursor()
+	ed.setStatus("Cut a line")
+	ed.setRegister(chars, true)
+}
+
+// Copy the word under the cursor.
+func (ed *Editor) copyWord() {
+	if ed.numRows() == 0 {
+		return
+	}
+	word := ed.wordAt(ed.row(ed.cy).chars, ed.cx)
+	if word == "" {
+		ed.setStatus("No word under the cursor")
+		return
+	}
+	ed.setStatus("Copied %s", word)
+	ed.setRegister(word, false)
+}
+
+// Put back what was copied or cut, yank of the yanks or -1: lines below the
+// cursor line, with the cursor on the first of them, other text at the
+// cursor.
+func (ed *Editor) put(reg register, yank int) {
+	if !ed.checkWritable() {
+		return
+	}
+	if reg.text == "" && !reg.linewise {
+		ed.setStatus("Nothing to paste")
+		return
+	}
+	if reg.linewise {
+		at := ed.cy + 1
+		if ed.numRows() == 0 {
+			at = 0
+		}
+		ed.insertRows(at, strings.Split(reg.text, "\n"))
+		ed.cy = at
+		ed.cx = firstNonBlank(ed.rows[at].chars)
+	} else {
+		ed.paste(reg.text)
+	}
+	// The paste is a group of its own already, for yankpop to tell
+	// nothing changed since.
+	ed.breakUndo()
+	ed.lastPaste = lastPaste{ed.undoState(), ed.cy, ed.cx, yank}
+}
+
+// Right after a paste, replace what it put in with the yank before it,
+// going round to the last one after the oldest, like yank-pop in Emacs.
+func (ed *Editor) yankPop() {
+	p := ed.lastPaste
+	if p.state == 0 || p.state != ed.undoState() || p.cy != ed.cy || p.cx != ed.cx || len(ed.undoStack) == 0 {
+		ed.setStatus("Nothing just pasted to replace")
+		return
+	}
+	if len(ed.yanks) == 0 || (len(ed.yanks) == 1 && p.yank == 0) {
+		ed.setStatus("No other yank to paste")
+		return
+	}
+	ed.undo()
+	// Undone from where the paste went in.
+	ed.breakUndo()
+	i := (p.yank + 1) % len(ed.yanks)
+	ed.put(ed.yanks[i], i)
+	ed.setStatus("Yank %d of %d", i+1, len(ed.yanks))
+}
@@ -0,0 +1,199 @@
+package editor
+
+import (
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCopyAndPaste(t *testing.T) {
+	tests := []struct {
+		name, text, keys, want string
+		cy, cx                 int
+	}{
+		{"line below", "a\n  b\nc\n", "\x1b[By\x1b[Bp", "a\n  b\nc\n  b\n", 3, 2},
+		{"cut line", "a\nb\nc\n", "\x0b\x1b[Bp", "b\nc\na\n", 2, 0},
+		{"cut the last line left", "a\n", "\x0bp", "\na\n", 1, 0},
+		{"empty line", "\nb\n", "y\x1b[Bp", "\nb\n\n", 2, 0},
+		{"word at the cursor", "foo bar\n", "\x1b[C:copyword\r\x1b[Fp", "foo barfoo\n", 0, 10},
+		{"again", "a\nb\n", "yp.", "a\na\na\nb\n", 2, 0},
+		{"undone in one", "a\nb\n", "\x0b\x0bp" + "u", "\n", 0, 0},
+		{"nothing copied", "a\n", "p", "a\n", 0, 0},
+	}
+	for _, tt := range tests {
+		ed, _ := runKeys(t, DefaultConfig(), writeTemp(t, "a.txt", tt.text), tt.keys)
+		if got := ed.Contents(); got != tt.want || ed.cy != tt.cy || ed.cx != tt.cx {
+			t.Errorf("%s: %q with the cursor at %d:%d, want %q at %d:%d", tt.name, got, ed.cy, ed.cx, tt.want, tt.cy, tt.cx)
+		}
+	}
+
+	// Copying works in a file opened read-only, pasting doesn't.
+	cfg := DefaultConfig()
+	cfg.confirmSize = 1
+	ed, _ := runKeys(t, cfg, writeTemp(t, "big.txt", "a\nb\n"), "r\ryp")
+	if ed.lastYank().text != "a" || !ed.lastYank().linewise || ed.numRows() != 2 {
+		t.Errorf("register %+v, %d rows after pasting read-only", ed.lastYank(), ed.numRows())
+	}
+}
+
+func TestCopyToClipboard(t *testing.T) {
+	long := strings.Repeat("a", 70000)
+	tests := []struct {
+		name, text, keys string
+		osc52            bool
+		clip             string
+	}{
+		{"line", "one\ntwo\n", "\x1b[By", true, "two\n"},
+		{"cut", "one\ntwo\n", "\x0b", true, "one\n"},
+		{"word", "foo bar\n", ":copyword\r", true, "foo"},
+		{"off", "one\n", "y", false, ""},
+		{"in pieces", long + "\n", "y", true, long + "\n"},
+		{"too big", long + long + "\n", "y", true, ""},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.osc52 = tt.osc52
+		rec := &frameRecorder{}
+		ed, _, err := RunScript(cfg, writeTemp(t, "a.txt", tt.text), strings.NewReader(tt.keys), rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := strings.Join(rec.frames, "")
+		sent := strings.Contains(out, "\x1b]52;")
+		if tt.clip == "" {
+			if sent {
+				t.Errorf("%s: sent to the clipboard", tt.name)
+			}
+			continue
+		}
+		seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(tt.clip)) + "\a"
+		if !strings.Contains(out, seq) {
+			t.Errorf("%s: %q not sent", tt.name, seq[:clamp(len(seq), 0, 40)])
+		}
+		for _, w := range rec.frames {
+			if strings.Contains(w, "YWFh") && len(w) > OSC52_CHUNK {
+				t.Errorf("%s: %d bytes of the sequence in one write", tt.name, len(w))
+			}
+		}
+		if ed.lastYank().text+"\n" != tt.clip && ed.lastYank().text != tt.clip {
+			t.Errorf("%s: register %q", tt.name, ed.lastYank().text)
+		}
+	}
+
+	// Too big for the clipboard is said, and it's still in the register.
+	cfg := DefaultConfig()
+	cfg.osc52 = true
+	ed, _ := runKeys(t, cfg, writeTemp(t, "a.txt", long+long+"\n"), "y")
+	if ed.lastYank().text != long+long || ed.statusmsg != "Too big for the terminal's clipboard, only copied in exa" {
+		t.Errorf("status %q, %d bytes in the register", ed.statusmsg, len(ed.lastYank().text))
+	}
+}
+
+func TestPasteClipboard(t *testing.T) {
+	answer := func(text, end string) string {
+		return "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + end
+	}
+	tests := []struct {
+		name, keys, want string
+		cy, cx           int
+	}{
+		{"at the cursor", "\x1b[Cp" + answer("xy", "\a"), "axyb\nc\n", 0, 3},
+		{"ended by ST", "p" + answer("xy", "\x1b\\"), "xyab\nc\n", 0, 2},
+		{"lines", "p" + answer("x\ny\n", "\a"), "x\ny\nab\nc\n", 2, 0},
+		{"keys typed before the answer", "p\x1b[B" + answer("xy", "\a"), "xyab\nc\n", 1, 1},
+		{"undone in one", "p" + answer("x\ny\n", "\a") + "u", "ab\nc\n", 0, 0},
+		{"no answer", "yp", "ab\nab\nc\n", 1, 0},
+		{"a bad answer", "yp\x1b]52;c;!!\a", "ab\nab\nc\n", 1, 0},
+		// What exa copied itself goes back in as it was copied, lines
+		// as lines.
+		{"copied here", "y\x1b[Cp" + answer("ab\n", "\a"), "ab\nab\nc\n", 1, 0},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.osc52, cfg.osc52Paste = true, true
+		ed, _ := runKeys(t, cfg, writeTemp(t, "a.txt", "ab\nc\n"), tt.keys)
+		if got := ed.Contents(); got != tt.want || ed.cy != tt.cy || ed.cx != tt.cx {
+			t.Errorf("%s: %q with the cursor at %d:%d, want %q at %d:%d", tt.name, got, ed.cy, ed.cx, tt.want, tt.cy, tt.cx)
+		}
+	}
+
+	// Only asked with osc52paste on.
+	rec := &frameRecorder{}
+	if _, _, err := RunScript(DefaultConfig(), writeTemp(t, "a.txt", "ab\n"), strings.NewReader("yp"), rec); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(strings.Join(rec.frames, ""), "\x1b]52;c;?") {
+		t.Error("clipboard asked for with osc52paste off")
+	}
+
+	// An answer after the timeout is too late for the paste, and isn't
+	// taken for keys either.
+	in, w := io.Pipe()
+	cfg := DefaultConfig()
+	cfg.osc52Paste = true
+	ed := New(cfg, in, ioutil.Discard)
+	ed.size = func() (int, int, error) { return DEFAULT_WIDTH, DEFAULT_HEIGHT, nil }
+	ed.updateSize()
+	if err := ed.openArg(writeTemp(t, "a.txt", "ab\n")); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		ed.Run()
+		close(done)
+	}()
+	start := time.Now()
+	io.WriteString(w, "yp")
+	time.Sleep(OSC52_TIMEOUT + 200*time.Millisecond)
+	io.WriteString(w, answer("late", "\a")+insertKey+"z")
+	w.Close()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("editor still running after its input ended")
+	}
+	if got, want := ed.Contents(), "ab\nzab\n"; got != want {
+		t.Errorf("after a late answer %q, want %q", got, want)
+	}
+	if time.Since(start) < OSC52_TIMEOUT {
+		t.Errorf("paste didn't wait for an answer")
+	}
+}
+
+func TestYankPop(t *testing.T) {
+	// Copies of the lines a, b and c, the last first in the ring, and
+	// then the word x.
+	copies := "y\x1b[By\x1b[By\x1b[B:copyword\r"
+	tests := []struct {
+		name   string
+		ring   int
+		keys   string
+		want   string
+		cy, cx int
+	}{
+		{"last yank", 10, copies + "p", "a\nb\nc\nxx y\n", 3, 1},
+		{"line before it", 10, copies + "p\x19", "a\nb\nc\nx y\nc\n", 4, 0},
+		{"older", 10, copies + "p\x19\x19\x19", "a\nb\nc\nx y\na\n", 4, 0},
+		{"round to the last", 10, copies + "p\x19\x19\x19\x19", "a\nb\nc\nxx y\n", 3, 1},
+		{"ring cut to size", 2, copies + "p\x19\x19", "a\nb\nc\nxx y\n", 3, 1},
+		{"undone whole", 10, copies + "p\x19u", "a\nb\nc\nx y\n", 3, 0},
+		{"not after a paste", 10, copies + "\x19", "a\nb\nc\nx y\n", 3, 0},
+		{"not after moving", 10, copies + "p\x1b[D\x19", "a\nb\nc\nxx y\n", 3, 0},
+		{"not after a change", 10, copies + "p\x7f\x19", "a\nb\nc\nx y\n", 3, 0},
+		{"only one yank", 10, "yp\x19", "a\na\nb\nc\nx y\n", 1, 0},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.yankRing = tt.ring
+		ed, _ := runKeys(t, cfg, writeTemp(t, "a.txt", "a\nb\nc\nx y\n"), tt.keys)
+		if got := ed.Contents(); got != tt.want || ed.cy != tt.cy || ed.cx != tt.cx {
+			t.Errorf("%s: %q with the cursor at %d:%d, want %q at %d:%d", tt.name, got, ed.cy, ed.cx, tt.want, tt.cy, tt.cx)
+		}
+		if len(ed.yanks) > tt.ring {
+			t.Errorf("%s: %d yanks kept, want %d", tt.name, len(ed.yanks), tt.ring)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package editor
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Remember the size and modification time of the file as it is on disk now,
+// to notice when something else changes it.
+func (ed *Editor) recordDiskState() {
+	ed.diskSize, ed.diskTime = -1, time.Time{}
+	if fi, err := os.Stat(ed.filename); err == nil {
+		ed.diskSize, ed.diskTime = fi.Size(), fi.ModTime()
+	}
+}
+
+// Whether the file changed on disk since it was opened or saved. A file that
+// was never on disk, or is gone now, doesn't count.
+func (ed *Editor) changedOnDisk() bool {
+	if ed.filename == "" || ed.diskSize < 0 {
+		return false
+	}
+	fi, err := os.Stat(ed.filename)
+	return err == nil && (fi.Size() != ed.diskSize || !fi.ModTime().Equal(ed.diskTime))
+}
+
+// Deal with the file having changed on disk: with autoreload an unmodified
+// buffer is reloaded quietly, otherwise ask. Saying no keeps the buffer and
+// doesn't ask again until the file changes once more, though saving asks
+// before overwriting the change.
+func (ed *Editor) checkDiskChange() {
+	if !ed.changedOnDisk() {
+		return
+	}
+	if !ed.dirty && ed.cfg.autoReload {
+		ed.reload()
+		ed.setStatus("Reloaded %s, it changed on disk", ed.displayName())
+		return
+	}
+	question := "%s changed on disk, reload? (y/n) "
+	if ed.dirty {
+		question = "%s changed on disk, reload and lose your changes? (y/n) "
+	}
+	answer, ok := ed.prompt(fmt.Sprintf(question, ed.displayName()), nil)
+	if ok && (answer == "y" || answer == "yes") {
+		ed.reload()
+		ed.setStatus("Reloaded %s", ed.displayName())
+		return
+	}
+	ed.recordDiskState()
+	ed.diskNewer = true
+}
+
+// Before saving over a file changed on disk since it was read, show what
+// saving would change in the output pane and ask. Return whether to go
+// ahead, without asking if the file already holds what the buffer does.
+func (ed *Editor) confirmOverwrite() bool {
+	if !ed.diffWithDisk() {
+		return true
+	}
+	answer, ok := ed.prompt(fmt.Sprintf("%s changed on disk, overwrite it? (y/n) ", ed.displayName()), nil)
+	if !ok || (answer != "y" && answer != "yes") {
+		// The diff stays up to look through.
+		ed.fail("Not written")
+		return false
+	}
+	if ed.pane != nil && ed.pane.diff {
+		ed.closePane()
+	}
+	return true
+}
+
+// Read the file again, keeping the cursor and scroll position as far as the
+// new contents allow.
+func (ed *Editor) reload() {
+	filename := ed.filename
+	cy, cx, rowoff, coloff := ed.cy, ed.cx, ed.rowoff, ed.coloff
+	// A file opened read-only from disk stays that way.
+	lazy := ed.lazy != nil
+	ed.closeBuffer()
+	if err := ed.open(filename, lazy); err != nil {
+		ed.filename = filename
+		ed.fail("Can't reload %s: %v", filename, err)
+		return
+	}
+	ed.cy, ed.cx, ed.rowoff, ed.coloff = cy, cx, rowoff, coloff
+	ed.clampCursor()
+}
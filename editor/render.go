@@ -0,0 +1,442 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Redraw the screen. Each terminal line is rendered on its own and compared
+// with the previous frame, so usually only the lines that changed (e.g. the
+// status bar after a cursor move) are sent. Everything is redrawn after
+// scrolling or resizing, or when most lines changed anyway.
+func (ed *Editor) refresh() {
+	ed.updateSize()
+	ed.gutter = clamp(ed.gutterWidth(), 0, ed.width-1)
+	ed.mapWidth = clamp(ed.minimapWidth(), 0, ed.width-ed.gutter-1)
+	if ed.browser != nil {
+		ed.browserScroll()
+	} else if ed.hex != nil {
+		ed.hexScroll()
+	} else {
+		ed.scroll()
+		ed.markVisible()
+		ed.blockPair = ed.findBlockPair()
+	}
+	if ed.pane != nil {
+		ed.paneScroll()
+	}
+	lines := ed.drawLines(ed.frameLines[:0])
+
+	last := ed.lastFrame
+	full := len(last) != len(lines) || ed.width != ed.lastWidth ||
+		ed.rowoff != ed.lastRowoff || ed.coloff != ed.lastColoff
+	if !full {
+		changed := 0
+		for y := range lines {
+			if lines[y] != last[y] {
+				changed++
+			}
+		}
+		full = changed > len(lines)/2
+	}
+
+	// Build the whole frame in one reused buffer and write it at once, so
+	// the terminal never shows a half drawn frame.
+	ab := &ed.frame
+	ab.Reset()
+	// Hide cursor
+	if ed.cfg.hideCursor {
+		ab.WriteString("\x1b[?25l")
+	}
+	for y, line := range lines {
+		if full || line != last[y] {
+			// <esc>[y;1H position the cursor at the start of line y.
+			// row and column number starts with 1.
+			fmt.Fprintf(ab, "\x1b[%d;1H", y+1)
+			ab.WriteString(line)
+		}
+	}
+
+	// Reposition cursor after draw. Note: terminal coordinate is index 1
+	cursorY, cursorX := ed.visibleLines(ed.rowoff, ed.cy, ed.screenrows), ed.rx-ed.coloff+ed.gutter
+	if ed.browser != nil {
+		cursorY, cursorX = ed.browser.cursor-ed.browser.top, 0
+	} else if ed.hex != nil {
+		cursorY, cursorX = ed.hexCursor()
+	}
+	if ed.pane != nil && ed.pane.focused {
+		cursorY, cursorX = ed.screenrows+1+ed.pane.cursor-ed.pane.top, 0
+	}
+	fmt.Fprintf(ab, "\x1b[%d;%dH", cursorY+1, cursorX+1)
+	// Unhide cursor
+	if ed.cfg.hideCursor {
+		ab.WriteString("\x1b[?25h")
+	}
+	ed.out.Write(ab.Bytes())
+
+	// Keep this frame to diff the next one against. The slices swap roles
+	// so neither is reallocated every frame.
+	ed.frameLines, ed.lastFrame = last, lines
+	ed.lastWidth, ed.lastRowoff, ed.lastColoff = ed.width, ed.rowoff, ed.coloff
+	ed.lastDrawn = time.Now()
+}
+
+// Render the screen as it is now, one string per terminal line with the
+// escape sequences that draw it, appended to lines. The scroll offsets are
+// used as they are, refresh brings them up to date first.
+func (ed *Editor) drawLines(lines []string) []string {
+	filerow := ed.rowoff
+	for y := 0; y < ed.screenrows; y++ {
+		ed.linebuf.Reset()
+		if ed.browser != nil {
+			ed.drawBrowserRow(&ed.linebuf, y)
+		} else if ed.hex != nil {
+			ed.drawHexRow(&ed.linebuf, y)
+		} else {
+			ed.drawRow(&ed.linebuf, y, filerow)
+			ed.drawMinimap(&ed.linebuf, y)
+		}
+		lines = append(lines, ed.linebuf.String())
+		filerow = ed.foldEnd(filerow) + 1
+	}
+	for y := 0; y < ed.paneRows(); y++ {
+		ed.linebuf.Reset()
+		ed.drawPaneRow(&ed.linebuf, y)
+		lines = append(lines, ed.linebuf.String())
+	}
+	ed.linebuf.Reset()
+	ed.drawStatusBar(&ed.linebuf)
+	lines = append(lines, ed.linebuf.String())
+	for y := 0; y < ed.msgRows(); y++ {
+		ed.linebuf.Reset()
+		ed.drawMessageBar(&ed.linebuf, y)
+		lines = append(lines, ed.linebuf.String())
+	}
+	// A screen too short for the bars shows the text rows it has room for.
+	if len(lines) > ed.height {
+		lines = lines[:ed.height]
+	}
+	return lines
+}
+
+// Forget the last frame so the next refresh redraws the whole screen, e.g.
+// after something else wrote to the terminal.
+func (ed *Editor) invalidateFrame() {
+	ed.lastFrame = nil
+}
+
+// Handle drawing screen line y, showing filerow of the buffer of text being
+// edited. Draws eobchar, a tilde by default, in rows past the end of the
+// file, which means that row is not part of the file and can’t contain any
+// text.
+func (ed *Editor) drawRow(ab *bytes.Buffer, y, filerow int) {
+	if filerow < ed.numRows() {
+		// Draw the visible slice of the row, cut at the screen edge.
+		// Only index math here, the row itself is never copied.
+		row := ed.row(filerow)
+		ed.drawGutter(ab, row, filerow)
+		width := ed.textWidth()
+		start, end := ed.coloff, ed.coloff+width
+		if start > len(row.render) {
+			start = len(row.render)
+		}
+		if end > len(row.render) {
+			end = len(row.render)
+		}
+		// Text cut off at either edge is shown by a marker in the edge
+		// column, in place of the character there.
+		screen := 0
+		if ed.coloff > 0 && len(row.render) > 0 {
+			ab.WriteString("\x1b[" + ed.hlColor(HL_EXTENDS) + "m" + ed.cfg.precedesChar + "\x1b[m")
+			screen++
+			if start < end {
+				start++
+			}
+		}
+		// On a one column screen the marker on the left takes it all.
+		cut := len(row.render) > ed.coloff+width && screen < width
+		if cut {
+			end--
+		}
+		end = clamp(end, start, len(row.render))
+		screen += end - start
+		guides := 0
+		if ed.cfg.indentGuides {
+			guides = row.indent()
+		}
+		trail := len(row.render)
+		if ed.cfg.markTrailing && (filerow != ed.cy || ed.cfg.markTrailingCursor) {
+			trail = len(strings.TrimRight(row.render, " "))
+		}
+		// The crosshair shades the cursor row where nothing else is
+		// drawn, the whole width of the screen. Rows with a diagnostic
+		// are shaded the same way.
+		shade := HL_NORMAL
+		if ed.cfg.crosshair && filerow == ed.cy {
+			shade = HL_CROSSHAIR
+		} else if row.diag != "" {
+			shade = HL_DIAG_LINE
+		}
+		fill := ed.hlColor(shade)
+		hl, copied := row.hl[start:end], false
+		for _, s := range ed.blockPair {
+			if s.row != filerow {
+				continue
+			}
+			if !copied {
+				// Marked on a copy, the pair moves with the cursor.
+				ed.pairHl = append(ed.pairHl[:0], hl...)
+				hl, copied = ed.pairHl, true
+			}
+			from, to := row.cxToRx(s.start, ed.cfg.tabStop)-start, row.cxToRx(s.end, ed.cfg.tabStop)-start
+			for j := clamp(from, 0, len(hl)); j < clamp(to, 0, len(hl)); j++ {
+				hl[j] = HL_PAIR
+			}
+		}
+		// The cursors besides the main one, at the end of the line on a
+		// space of their own.
+		atEnd := false
+		for _, c := range ed.cursors {
+			if c.y != filerow {
+				continue
+			}
+			if !copied {
+				ed.pairHl = append(ed.pairHl[:0], hl...)
+				hl, copied = ed.pairHl, true
+			}
+			if rx := row.cxToRx(c.x, ed.cfg.tabStop) - start; rx >= 0 && rx < len(hl) {
+				hl[rx] = HL_CURSOR
+			} else if rx+start == len(row.render) && end == len(row.render) && screen < width {
+				atEnd = true
+			}
+		}
+		ed.drawHighlighted(ab, row.render[start:end], hl, start, guides, trail, shade)
+		if atEnd {
+			ab.WriteString("\x1b[" + ed.hlColor(HL_CURSOR) + "m \x1b[m")
+			screen++
+		}
+		switch last := ed.foldEnd(filerow); {
+		case cut:
+			ab.WriteString("\x1b[" + ed.hlColor(HL_EXTENDS) + "m" + ed.cfg.extendsChar + "\x1b[m")
+		case last > filerow:
+			ed.drawFoldMarker(ab, last-filerow, width-screen)
+		default:
+			ed.drawColorColumns(ab, ed.coloff+screen, fill)
+		}
+		if fill != "" {
+			// Clearing the rest of the line takes the current background,
+			// so this does the clearing below too.
+			ab.WriteString("\x1b[" + fill + "m\x1b[K\x1b[m")
+			return
+		}
+	} else if ed.numRows() == 0 && ed.filename == "" {
+		ed.drawWelcome(ab, y)
+	} else {
+		ab.WriteString(ed.cfg.eobChar)
+	}
+	// Clear line. <esc>[K clear from cursor the end of line.
+	ab.WriteString("\x1b[K")
+}
+
+// Draw screen line y of the welcome screen shown when no file is open: the
+// welcome banner a third down the screen, each of its lines centered, and
+// tildes as past the end of a file.
+func (ed *Editor) drawWelcome(ab *bytes.Buffer, y int) {
+	ab.WriteString(ed.cfg.eobChar)
+	if ed.cfg.welcome == "" {
+		return
+	}
+	lines := strings.Split(ed.cfg.welcome, `\n`)
+	i := y - ed.screenrows/3
+	if i < 0 || i >= len(lines) {
+		return
+	}
+	if ed.cfg.eobChar == "" {
+		// Keep the column, so banners center the same.
+		ab.WriteString(" ")
+	}
+	// The tilde takes the first column, the line gets the rest.
+	message := lines[i]
+	message = message[:clamp(len(message), 0, ed.width-1)]
+	// Center the message. Divide the screen width by half and subtract
+	// half of the string length to get padding size.
+	padding := clamp((ed.width-len(message))/2, 1, ed.width)
+	ab.WriteString(strings.Repeat(" ", padding-1))
+	ab.WriteString(message)
+}
+
+// Draw the marker after a fold header saying how many rows it hides, in
+// the room left on the line.
+func (ed *Editor) drawFoldMarker(ab *bytes.Buffer, hidden, room int) {
+	marker := fmt.Sprintf(" +%d lines", hidden)
+	marker = marker[:clamp(len(marker), 0, room)]
+	ab.WriteString("\x1b[" + ed.hlColor(HL_FOLD) + "m" + marker + "\x1b[m")
+}
+
+// Whether column col of the rendered rows is one of the color columns, or
+// the cursor column of the crosshair.
+func (ed *Editor) isColorColumn(col int) bool {
+	if ed.cfg.crosshair && col == ed.rx {
+		return true
+	}
+	for _, c := range ed.cfg.colorColumns {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
+// Mark the color columns past the end of a row's text, which ends at column
+// from of the rendered row. The gaps between them are drawn in the fill
+// colors, if any.
+func (ed *Editor) drawColorColumns(ab *bytes.Buffer, from int, fill string) {
+	columns := ed.cfg.colorColumns
+	if ed.cfg.crosshair {
+		columns = append([]int{ed.rx}, columns...)
+		sort.Ints(columns)
+	}
+	for _, c := range columns {
+		if c < from || c < ed.coloff {
+			continue
+		}
+		if c >= ed.coloff+ed.textWidth() {
+			break
+		}
+		gap := strings.Repeat(" ", c-from)
+		if fill != "" {
+			gap = "\x1b[" + fill + "m" + gap + "\x1b[m"
+		}
+		ab.WriteString(gap)
+		ab.WriteString("\x1b[" + ed.hlColor(HL_COLORCOLUMN) + "m \x1b[m")
+		from = c + 1
+	}
+}
+
+// Write text switching colors wherever its highlight class changes. text
+// starts at column start of the row. Spaces at tab stops in the first guides
+// columns are drawn as indentation guides, and color columns get their
+// background on top of the text's own colors. With marklong, text past
+// maxwidth is drawn in a warning color instead of its highlight, and so is
+// whitespace from column trail on. Unhighlighted text is drawn as shade,
+// e.g. the crosshair on the cursor row, or HL_NORMAL.
+func (ed *Editor) drawHighlighted(ab *bytes.Buffer, text string, hl []uint8, start, guides, trail int, shade uint8) {
+	current := HL_NORMAL
+	for i := 0; i < len(text); i++ {
+		col := start + i
+		class := hl[i]
+		if class == HL_NORMAL {
+			class = shade
+		}
+		if ed.cfg.markLong && col >= ed.cfg.maxWidth {
+			class = HL_LONG
+		}
+		if col >= trail {
+			class = HL_TRAILING
+		}
+		if class != current {
+			// <esc>[m resets attributes before setting the new ones.
+			current = class
+			ab.WriteString("\x1b[m")
+			if color := ed.hlColor(current); color != "" {
+				ab.WriteString("\x1b[" + color + "m")
+			}
+		}
+		switch {
+		case col < guides && col%ed.indentWidth() == 0 && text[i] == ' ':
+			// Faint, then back to the row's own colors.
+			ab.WriteString("\x1b[2m" + ed.cfg.guideChar + "\x1b[m")
+		case ed.isColorColumn(col):
+			ab.WriteString("\x1b[" + ed.hlColor(HL_COLORCOLUMN) + "m")
+			ab.WriteByte(text[i])
+			ab.WriteString("\x1b[m")
+		default:
+			ab.WriteByte(text[i])
+			continue
+		}
+		if color := ed.hlColor(current); color != "" {
+			ab.WriteString("\x1b[" + color + "m")
+		}
+	}
+	if current != HL_NORMAL {
+		ab.WriteString("\x1b[m")
+	}
+}
+
+// Draw an inverted bar with the filename on the left, indentation, filetype
+// and cursor line on the right.
+func (ed *Editor) drawStatusBar(ab *bytes.Buffer) {
+	name := ed.statusName()
+	modified := ""
+	if ed.readOnly {
+		modified = " [RO]"
+	}
+	if ed.dirty {
+		modified += " (modified)"
+	}
+	branch := ""
+	if ed.branch != "" {
+		branch = " [" + ed.branch + "]"
+	}
+	left := fmt.Sprintf("%s%s - %d lines%s%s", name, branch, ed.numRows(), modified, ed.typingMarker())
+	filetype := "no ft"
+	if ed.syntax != nil {
+		filetype = ed.syntax.filetype
+	}
+	right := fmt.Sprintf("%s | %s | %d/%d", ed.indentInfo(), filetype, ed.cy+1, ed.numRows())
+	if ed.statusHook.text != "" {
+		right = ed.statusHook.text + " | " + right
+	}
+	if ed.cfg.statusLine != "" {
+		left, right = ed.expandStatus(ed.cfg.statusLine)
+	}
+	if ed.browser != nil {
+		left, right = ed.browserStatus()
+	} else if ed.hex != nil {
+		left, right = ed.hexStatus()
+	}
+	left = left[:clamp(len(left), 0, ed.width)]
+	// <esc>[7m switch to inverted colors, <esc>[m back to normal.
+	ab.WriteString("\x1b[7m")
+	ab.WriteString(left)
+	// Right-align the cursor position if it fits.
+	if gap := ed.width - len(left) - len(right); gap >= 0 {
+		ab.WriteString(strings.Repeat(" ", gap))
+		ab.WriteString(right)
+	} else {
+		ab.WriteString(strings.Repeat(" ", ed.width-len(left)))
+	}
+	ab.WriteString("\x1b[m")
+}
+
+// Name of the buffer for the status bar and messages.
+func (ed *Editor) displayName() string {
+	if ed.filename == "" {
+		return "[No Name]"
+	}
+	return ed.cfg.showPath(ed.filename)
+}
+
+// The display name cut to its first 20 characters for the status bar.
+func (ed *Editor) statusName() string {
+	name := []rune(ed.displayName())
+	if len(name) > 20 {
+		name = name[:20]
+	}
+	return string(name)
+}
+
+func (ed *Editor) setStatus(format string, a ...interface{}) {
+	ed.statusmsg = fmt.Sprintf(format, a...)
+	ed.statusmsgTime = time.Now()
+}
+
+// Like setStatus, for a command that didn't do what it was asked, which
+// batch mode stops at.
+func (ed *Editor) fail(format string, a ...interface{}) {
+	ed.setStatus(format, a...)
+	ed.failed = true
+}
@@ -0,0 +1,396 @@
+package editor
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Keeps each frame written to it.
+type frameRecorder struct {
+	frames []string
+}
+
+func (r *frameRecorder) Write(p []byte) (int, error) {
+	r.frames = append(r.frames, string(p))
+	return len(p), nil
+}
+
+func TestKeyBurstCoalescesFrames(t *testing.T) {
+	var text strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&text, "line %d\n", i)
+	}
+	path := writeTemp(t, "lines.txt", text.String())
+	rec := &frameRecorder{}
+	ed, _, err := RunScript(DefaultConfig(), path, strings.NewReader(strings.Repeat("\x1b[B", 50)), rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ed.cy != 50 {
+		t.Errorf("cursor on row %d after 50 downs, want 50", ed.cy)
+	}
+	// The first frame, one at the end of the burst, and the writes
+	// clearing the screen and resetting the terminal on the way out, maybe
+	// one more if the machine is slow.
+	if len(rec.frames) > 7 {
+		t.Errorf("%d writes for a burst of 50 keys, want a handful", len(rec.frames))
+	}
+	last := rec.frames[len(rec.frames)-3]
+	if !strings.Contains(last, "51/100") {
+		t.Errorf("last frame %q doesn't show the cursor on line 51", last)
+	}
+}
+
+// An editor on a new file name holding text, drawing frames of width by
+// height to the returned buffer, without colors.
+func frameEditor(t testing.TB, width, height int, name, text string) (*Editor, *bytes.Buffer) {
+	t.Helper()
+	path := writeTemp(t, name, text)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Opened by its base name, so the status bar doesn't show the
+	// temporary directory.
+	if err := os.Chdir(filepath.Dir(path)); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	cfg := DefaultConfig()
+	cfg.color = false
+	out := &bytes.Buffer{}
+	ed := New(cfg, strings.NewReader(""), out)
+	ed.size = func() (int, int, error) { return width, height, nil }
+	if err := ed.openArg(name); err != nil {
+		t.Fatal(err)
+	}
+	ed.setStatus("")
+	return ed, out
+}
+
+func TestFrameBytes(t *testing.T) {
+	ed, out := frameEditor(t, 40, 6, "a.go", "package a\n\nfunc\tf() {}\n\x07\n")
+	ed.setStatus("hello")
+	ed.refresh()
+	want := "\x1b[?25l" +
+		"\x1b[1;1Hpackage a\x1b[K" +
+		"\x1b[2;1H\x1b[K" +
+		"\x1b[3;1Hfunc    f() {}\x1b[K" +
+		"\x1b[4;1H\x1b[m\x1b[7m^G\x1b[m\x1b[K" +
+		"\x1b[5;1H\x1b[7ma.go - 4 lines           tabs | go | 1/4\x1b[m" +
+		"\x1b[6;1H\x1b[Khello" +
+		"\x1b[1;1H\x1b[?25h"
+	if got := out.String(); got != want {
+		t.Errorf("first frame\n%q, want\n%q", got, want)
+	}
+
+	// Moving the cursor redraws the status bar only.
+	out.Reset()
+	ed.cy, ed.cx = 2, 5
+	ed.refresh()
+	want = "\x1b[?25l" +
+		"\x1b[5;1H\x1b[7ma.go - 4 lines           tabs | go | 3/4\x1b[m" +
+		"\x1b[3;9H\x1b[?25h"
+	if got := out.String(); got != want {
+		t.Errorf("frame after moving\n%q, want\n%q", got, want)
+	}
+}
+
+func TestFrameEmptyBuffer(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.color, cfg.hideCursor, cfg.welcome = false, false, "hi"
+	out := &bytes.Buffer{}
+	ed := New(cfg, strings.NewReader(""), out)
+	ed.size = func() (int, int, error) { return 10, 5, nil }
+	ed.setStatus("")
+	ed.refresh()
+	// The welcome banner a third down, rows past the end marked.
+	want := "\x1b[1;1H~\x1b[K" +
+		"\x1b[2;1H~   hi\x1b[K" +
+		"\x1b[3;1H~\x1b[K" +
+		"\x1b[4;1H\x1b[7m[No Name] \x1b[m" +
+		"\x1b[5;1H\x1b[K" +
+		"\x1b[1;1H"
+	if got := out.String(); got != want {
+		t.Errorf("frame\n%q, want\n%q", got, want)
+	}
+}
+
+func TestFrameWrappedMessage(t *testing.T) {
+	ed, out := frameEditor(t, 10, 8, "a.txt", "one\ntwo\nthree\n")
+	ed.cfg.longMsg = "wrap"
+	ed.setStatus("a message of 24 letters.")
+	ed.refresh()
+	// Three message rows leave four for text.
+	lines := ed.lastFrame
+	want := []string{
+		"one\x1b[K",
+		"two\x1b[K",
+		"three\x1b[K",
+		"~\x1b[K",
+		"\x1b[7ma.txt - 3 \x1b[m",
+		"\x1b[Ka message ",
+		"\x1b[Kof 24 lett",
+		"\x1b[Kers.",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("%d lines drawn, want %d: %q", len(lines), len(want), lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d %q, want %q", i+1, lines[i], want[i])
+		}
+	}
+	if !strings.HasSuffix(out.String(), "\x1b[1;1H\x1b[?25h") {
+		t.Errorf("frame %q doesn't end with the cursor on the first row", out.String())
+	}
+}
+
+// The text of each screen line a frame drew, by the row it was positioned
+// at, without the escape sequences.
+func screenLines(frame string) map[int]string {
+	lines := map[int]string{}
+	row := 0
+	for i := 0; i < len(frame); {
+		if frame[i] != 0x1b {
+			lines[row] += frame[i : i+1]
+			i++
+			continue
+		}
+		// A CSI sequence up to its final byte.
+		j := i + 2
+		for j < len(frame) && (frame[j] < 0x40 || frame[j] > 0x7e) {
+			j++
+		}
+		if j < len(frame) && frame[j] == 'H' {
+			fmt.Sscanf(frame[i+2:j], "%d", &row)
+		}
+		i = j + 1
+	}
+	return lines
+}
+
+// An editor with no file open, drawing frames of the size.
+func welcomeEditor(width, height int, welcome string) (*Editor, *bytes.Buffer) {
+	cfg := DefaultConfig()
+	cfg.color, cfg.welcome = false, welcome
+	out := &bytes.Buffer{}
+	ed := New(cfg, strings.NewReader(""), out)
+	ed.size = func() (int, int, error) { return width, height, nil }
+	ed.setStatus("")
+	return ed, out
+}
+
+func TestWelcomeBanner(t *testing.T) {
+	tests := []struct {
+		name, welcome string
+		width         int
+		// Text rows 1 to 4.
+		want []string
+	}{
+		{"centered lines", `hello\nab`, 10, []string{"~", "~ hello", "~   ab", "~"}},
+		{"cut to fit after the tilde", "hello", 4, []string{"~", "~hel", "~", "~"}},
+		{"off", "", 10, []string{"~", "~", "~", "~"}},
+	}
+	for _, tt := range tests {
+		ed, out := welcomeEditor(tt.width, 6, tt.welcome)
+		ed.refresh()
+		lines := screenLines(out.String())
+		for i, want := range tt.want {
+			if got := lines[i+1]; got != want {
+				t.Errorf("%s: row %d %q, want %q", tt.name, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestWelcomeAtWidthFive(t *testing.T) {
+	// Cutting the banner used to slice the whole frame being drawn.
+	for width := 1; width <= 5; width++ {
+		ed, out := welcomeEditor(width, 6, DefaultConfig().welcome)
+		ed.refresh()
+		for row, line := range screenLines(out.String()) {
+			if len(line) > width {
+				t.Errorf("width %d: row %d %q is wider than the screen", width, row, line)
+			}
+		}
+	}
+}
+
+func TestFrameAtOddSizes(t *testing.T) {
+	text := "short\n" + strings.Repeat("long line ", 20) + "\n\tx\n"
+	sizes := [][2]int{{1, 1}, {1, 5}, {2, 3}, {2, 10}, {100000, 3}, {20, 100000}}
+	for _, size := range sizes {
+		width, height := size[0], size[1]
+		ed, out := frameEditor(t, width, height, "a.txt", text)
+		ed.setStatus("a message longer than the narrow screens")
+		// Scrolled sideways on the long line, cut off at both edges.
+		ed.cy, ed.cx, ed.coloff = 1, 50, 40
+		ed.refresh()
+		lines := screenLines(out.String())
+		for row, line := range lines {
+			if row < 1 || row > height {
+				t.Errorf("%dx%d: drew on row %d", width, height, row)
+			}
+			// The « and » markers take more than a byte each.
+			if n := utf8.RuneCountInString(line); n > width {
+				t.Errorf("%dx%d: row %d is %d wide", width, height, row, n)
+			}
+		}
+		if width == 100000 {
+			// The status bar reaches across, with the position on the
+			// right.
+			bar := lines[height-1]
+			if len(bar) != width || !strings.HasSuffix(bar, "2/3") {
+				t.Errorf("%dx%d: status bar %d wide ending %q", width, height, len(bar), bar[clamp(len(bar)-10, 0, len(bar)):])
+			}
+		}
+	}
+}
+
+// A line of 50k characters, as in minified files and logs, with the cursor
+// in the middle of it.
+func longLineEditor(b *testing.B) (*Editor, *bytes.Buffer) {
+	line := strings.Repeat("abcdefghi ", 5000)
+	ed, out := frameEditor(b, 80, 24, "a.txt", "short\n"+line+"\nshort\n")
+	ed.cy, ed.cx = 1, len(line)/2
+	return ed, out
+}
+
+func BenchmarkLongLineTyping(b *testing.B) {
+	ed, _ := longLineEditor(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ed.insertChar('x')
+		ed.backspace()
+	}
+}
+
+func BenchmarkLongLineRender(b *testing.B) {
+	ed, out := longLineEditor(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		out.Reset()
+		ed.invalidateFrame()
+		ed.refresh()
+	}
+}
+
+func TestFrameRedrawsChangedLines(t *testing.T) {
+	text := strings.Repeat("a line of text\n", 50)
+	ed, out := frameEditor(t, 80, 24, "a.txt", text)
+	ed.refresh()
+	full := out.Len()
+
+	// Only the edited line and the status bar, which now says modified,
+	// are sent again.
+	rows := func() []int {
+		var rows []int
+		for y := 1; y <= 24; y++ {
+			if strings.Contains(out.String(), fmt.Sprintf("\x1b[%d;1H", y)) {
+				rows = append(rows, y)
+			}
+		}
+		return rows
+	}
+	out.Reset()
+	ed.cy, ed.cx = 3, 2
+	ed.insertChar('x')
+	ed.refresh()
+	if got := rows(); fmt.Sprint(got) != "[4 23]" {
+		t.Errorf("redrew rows %v after typing, want [4 23]", got)
+	}
+	if out.Len() > full/4 {
+		t.Errorf("sent %d bytes for one character, %d for the whole screen", out.Len(), full)
+	}
+	out.Reset()
+	ed.insertChar('y')
+	ed.refresh()
+	if got := rows(); fmt.Sprint(got) != "[4]" {
+		t.Errorf("redrew rows %v after typing again, want [4]", got)
+	}
+	out.Reset()
+	ed.refresh()
+	if got := rows(); len(got) != 0 {
+		t.Errorf("redrew rows %v without a change", got)
+	}
+
+	// Scrolling moves every line, so the whole screen goes out.
+	out.Reset()
+	ed.cy = 40
+	ed.refresh()
+	if got := rows(); len(got) != 24 {
+		t.Errorf("redrew rows %v after scrolling, want all 24", got)
+	}
+}
+
+func TestFrameControlCharacters(t *testing.T) {
+	ed, out := frameEditor(t, 40, 5, "a.txt", "a\x1b[2Jb\x07c\n")
+	ed.cx = 2
+	ed.refresh()
+	frame := out.String()
+	// Drawn in caret notation, inverted, and never sent as they are.
+	for _, want := range []string{"a\x1b[m\x1b[7m^[\x1b[m[2Jb", "\x1b[7m^G\x1b[mc"} {
+		if !strings.Contains(frame, want) {
+			t.Errorf("frame %q doesn't show %q", frame, want)
+		}
+	}
+	if strings.Contains(frame, "\x1b[2J") || strings.Contains(frame, "\x07") {
+		t.Errorf("frame %q has the control characters in it", frame)
+	}
+	// Each takes two columns, so the cursor after ^[ is in the fourth.
+	if !strings.HasSuffix(frame, "\x1b[1;4H\x1b[?25h") {
+		t.Errorf("frame ends %q, want the cursor in column 4", frame[len(frame)-20:])
+	}
+
+	// The bytes themselves are kept and saved.
+	path := writeTemp(t, "a.txt", "a\x1b[2Jb\x07c\n")
+	runKeys(t, DefaultConfig(), path, insertKey+"x\x1b:w\r")
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != "xa\x1b[2Jb\x07c\n" {
+		t.Errorf("saved %q, %v", data, err)
+	}
+}
+
+func TestFiletypeTabStop(t *testing.T) {
+	dir := filepath.Dir(writeTemp(t, "a.go", "\tx\n"))
+	if err := ioutil.WriteFile(filepath.Join(dir, "b.txt"), []byte("\tx\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	tests := []struct {
+		keys string
+		// Where x is drawn and the cursor on it.
+		row, cursor string
+	}{
+		{"\x1b[C", "    x", "\x1b[1;5H"},
+		// The next file goes back to the configured tab stop.
+		{":e b.txt\r\x1b[C", "        x", "\x1b[1;9H"},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.color = false
+		cfg.ftIndent = map[string]*indentStyle{"go": {width: 4}}
+		ed, _ := runKeys(t, cfg, "a.go", tt.keys)
+		out := &bytes.Buffer{}
+		ed.out = out
+		ed.invalidateFrame()
+		ed.refresh()
+		frame := out.String()
+		if line := screenLines(frame)[1]; line != tt.row || !strings.HasSuffix(frame, tt.cursor+"\x1b[?25h") {
+			t.Errorf("%q: row %q, frame ends %q, want %q with the cursor at %q", tt.keys, line, frame[len(frame)-20:], tt.row, tt.cursor)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package editor
+
+import "regexp"
+
+// Call fn with the range in chars of every match of the last search.
+// Empty matches are skipped, there is nothing to show or jump past.
+func (ed *Editor) eachMatch(chars string, fn func(start, end int)) {
+	ed.eachMatchOf(ed.search, ed.searchWords, chars, fn)
+}
+
+// Call fn with the range in chars of every match of re, with words set only
+// those that are whole words, as isSeparator has them.
+func (ed *Editor) eachMatchOf(re *regexp.Regexp, words bool, chars string, fn func(start, end int)) {
+	for _, m := range re.FindAllStringIndex(chars, -1) {
+		if m[0] == m[1] {
+			continue
+		}
+		if words && ((m[0] > 0 && !ed.isSeparator(chars[m[0]-1])) || (m[1] < len(chars) && !ed.isSeparator(chars[m[1]]))) {
+			continue
+		}
+		fn(m[0], m[1])
+	}
+}
+
+// How many matches of re start up to index x of row y, which makes n the
+// number of the match at the cursor counting from 1, and how many there are
+// in the whole buffer. ok is false when counting was cancelled.
+func (ed *Editor) countMatches(re *regexp.Regexp, words bool, y, x int) (n, total int, ok bool) {
+	for i := 0; i < ed.numRows(); i++ {
+		if ed.scanCancelled(i) {
+			return 0, 0, false
+		}
+		ed.eachMatchOf(re, words, ed.row(i).chars, func(start, end int) {
+			total++
+			if i < y || (i == y && start <= x) {
+				n++
+			}
+		})
+	}
+	return n, total, true
+}
+
+// Mark the matches of the last search in row, over any other highlight.
+func (ed *Editor) markMatches(row *Row) {
+	ed.eachMatch(row.chars, func(start, end int) {
+		from, to := row.cxToRx(start, ed.cfg.tabStop), row.cxToRx(end, ed.cfg.tabStop)
+		for j := from; j < to; j++ {
+			row.hl[j] = HL_MATCH
+		}
+	})
+}
+
+// Whether the matches of the last search are to be marked.
+func (ed *Editor) showMatches() bool {
+	return ed.search != nil && ed.searchShown && ed.cfg.hlSearch
+}
+
+// Compile a search for pattern, a regular expression, ignoring case with
+// ignorecase on.
+func (ed *Editor) compileSearch(pattern string) (*regexp.Regexp, error) {
+	if ed.cfg.ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// Prompt for a regular expression and move to its next match. With
+// hlsearch on, all its matches stay marked until nohl.
+func (ed *Editor) find() {
+	pattern, ok := ed.prompt("Search: ", nil)
+	if !ok || pattern == "" {
+		return
+	}
+	re, err := ed.compileSearch(pattern)
+	if err != nil {
+		ed.fail("Bad pattern: %v", err)
+		return
+	}
+	ed.search, ed.searchPattern, ed.searchWords, ed.searchShown = re, pattern, ed.cfg.wholeWord, true
+	// Marks of the old pattern are only cleared by highlighting again.
+	ed.updateRows()
+	ed.jumpMatch(1)
+}
+
+// Move the cursor to the next (dir 1) or previous (dir -1) match of the
+// last search, wrapping around the buffer, and say which of how many
+// matches it is, e.g. "[3/12]". Shows hidden matches again.
+func (ed *Editor) jumpMatch(dir int) {
+	if ed.search == nil {
+		ed.setStatus("No previous search")
+		return
+	}
+	if !ed.searchShown {
+		ed.searchShown = true
+		ed.updateRows()
+	}
+	n := ed.numRows()
+	for i := 0; i <= n && n > 0; i++ {
+		if ed.scanCancelled(i) {
+			return
+		}
+		y := ((ed.cy+dir*i)%n + n) % n
+		found := -1
+		ed.eachMatch(ed.row(y).chars, func(start, end int) {
+			// As in jumpTodo, the cursor row only counts past the cursor
+			// until the search wraps back to it.
+			if i == 0 && ((dir > 0 && start <= ed.cx) || (dir < 0 && start >= ed.cx)) {
+				return
+			}
+			if i == n && ((dir > 0 && start > ed.cx) || (dir < 0 && start < ed.cx)) {
+				return
+			}
+			if found < 0 || (dir > 0 && start < found) || (dir < 0 && start > found) {
+				found = start
+			}
+		})
+		if found >= 0 {
+			ed.cy, ed.cx = y, found
+			if n, total, ok := ed.countMatches(ed.search, ed.searchWords, y, found); ok {
+				ed.setStatus("[%d/%d] %s", n, total, ed.searchPattern)
+			}
+			return
+		}
+	}
+	ed.setStatus("No matches for %s", ed.searchPattern)
+}
+
+// Stop marking the matches of the last search, until the next one.
+func (ed *Editor) noHighlight() {
+	if ed.searchShown {
+		ed.searchShown = false
+		ed.updateRows()
+	}
+}
+
+// Turn ignorecase or wholeword, name, on or off, and say which. The last
+// search, n and N go on with, changes along.
+func (ed *Editor) toggleSearchOption(name string, b *bool) {
+	*b = !*b
+	ed.searchAgain()
+	ed.updateRows()
+	if *b {
+		ed.setStatus("%s on", name)
+	} else {
+		ed.setStatus("%s off", name)
+	}
+}
+
+// Compile the last search again for the current ignorecase and wholeword.
+func (ed *Editor) searchAgain() {
+	if ed.search == nil {
+		return
+	}
+	if re, err := ed.compileSearch(ed.searchPattern); err == nil {
+		ed.search = re
+	}
+	ed.searchWords = ed.cfg.wholeWord
+}
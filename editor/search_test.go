@@ -0,0 +1,123 @@
+package editor
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSearchCount(t *testing.T) {
+	path := writeTemp(t, "a.txt", "foo bar\nfoo\nbaz foo foo\n")
+	tests := []struct {
+		keys   string
+		cy, cx int
+		status string
+	}{
+		{"/foo\r", 1, 0, "[2/4] foo"},
+		{"/foo\rn", 2, 4, "[3/4] foo"},
+		{"/foo\rnnn", 0, 0, "[1/4] foo"},
+		{"/foo\rN", 0, 0, "[1/4] foo"},
+		{"/fo+\rNN", 2, 8, "[4/4] fo+"},
+		{"/nothing\r", 0, 0, "No matches for nothing"},
+	}
+	for _, tt := range tests {
+		ed, _ := runKeys(t, DefaultConfig(), path, tt.keys)
+		if ed.cy != tt.cy || ed.cx != tt.cx || ed.statusmsg != tt.status {
+			t.Errorf("%q: cursor at %d,%d, status %q, want %d,%d, %q", tt.keys, ed.cy, ed.cx, ed.statusmsg, tt.cy, tt.cx, tt.status)
+		}
+	}
+
+	// Asked about each one, the count goes down as matches are replaced.
+	out := &bytes.Buffer{}
+	cfg := DefaultConfig()
+	cfg.wholeWord = true
+	ed, _, err := RunScript(cfg, writeTemp(t, "b.txt", "foo foo\nfoobar foo\n"), strings.NewReader(":replaceword\rbar\ry\rn\ry\r"), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ed.Contents() != "bar foo\nfoobar bar\n" {
+		t.Errorf("replaced to %q", ed.Contents())
+	}
+	frames := out.String()
+	for _, want := range []string{"Replace with bar? [1/3]", "Replace with bar? [1/2]", "Replace with bar? [2/2]"} {
+		if !strings.Contains(frames, want) {
+			t.Errorf("%q never asked", want)
+		}
+		frames = frames[strings.Index(frames, want)+1:]
+	}
+}
+
+func TestReplaceWordUndo(t *testing.T) {
+	tests := []struct {
+		keys, want string
+		cy, cx     int
+	}{
+		{"y\rn\ry\ru", "bar foo\nfoobar foo\n", 1, 7},
+		{"y\rn\ry\ruu", "foo foo\nfoobar foo\n", 0, 0},
+		{"y\rn\ry\ruu\x12", "bar foo\nfoobar foo\n", 0, 0},
+		{"y\rn\ry\ruu\x12\x12", "bar foo\nfoobar bar\n", 1, 7},
+		{"n\ra\r", "foo bar\nfoobar bar\n", 1, 7},
+		{"n\ra\ru", "foo bar\nfoobar foo\n", 1, 7},
+		{"n\ra\ruu", "foo foo\nfoobar foo\n", 0, 4},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.wholeWord = true
+		ed, _ := runKeys(t, cfg, writeTemp(t, "b.txt", "foo foo\nfoobar foo\n"), ":replaceword\rbar\r"+tt.keys)
+		if got := ed.Contents(); got != tt.want || ed.cy != tt.cy || ed.cx != tt.cx {
+			t.Errorf("%q: %q with the cursor at %d:%d, want %q at %d:%d", tt.keys, got, ed.cy, ed.cx, tt.want, tt.cy, tt.cx)
+		}
+	}
+}
+
+func TestSearchToggles(t *testing.T) {
+	path := writeTemp(t, "a.txt", "foo Foo foobar FOO foo\n")
+	tests := []struct {
+		ignoreCase, wholeWord bool
+		keys                  string
+		cx                    int
+		status                string
+	}{
+		{false, false, "/foo\r", 8, "[2/3] foo"},
+		{true, false, "/foo\r", 4, "[2/5] foo"},
+		{false, true, "/foo\r", 19, "[2/2] foo"},
+		{true, true, "/foo\r", 4, "[2/4] foo"},
+		// Toggled after searching, n goes on with the new setting.
+		{false, false, "/foo\r:ignorecase\rn", 15, "[4/5] foo"},
+		{true, false, "/foo\r:wholeword\rn", 15, "[3/4] foo"},
+		{false, false, "/foo\r:set wholeword=on\rn", 19, "[2/2] foo"},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.ignoreCase, cfg.wholeWord = tt.ignoreCase, tt.wholeWord
+		ed, _ := runKeys(t, cfg, path, tt.keys)
+		if ed.cx != tt.cx || ed.statusmsg != tt.status {
+			t.Errorf("ignorecase %v, wholeword %v, %q: cursor at %d, status %q, want %d, %q", tt.ignoreCase, tt.wholeWord, tt.keys, ed.cx, ed.statusmsg, tt.cx, tt.status)
+		}
+	}
+
+	// A toggle says which way it went.
+	ed, _ := runKeys(t, DefaultConfig(), path, ":ignorecase\r")
+	if !ed.cfg.ignoreCase || ed.statusmsg != "ignorecase on" {
+		t.Errorf("ignorecase %v, status %q", ed.cfg.ignoreCase, ed.statusmsg)
+	}
+
+	// replaceword matches the same way.
+	replaced := []struct {
+		ignoreCase, wholeWord bool
+		want                  string
+	}{
+		{false, false, "x Foo xbar FOO x\n"},
+		{true, false, "x x xbar x x\n"},
+		{false, true, "x Foo foobar FOO x\n"},
+		{true, true, "x x foobar x x\n"},
+	}
+	for _, tt := range replaced {
+		cfg := DefaultConfig()
+		cfg.ignoreCase, cfg.wholeWord = tt.ignoreCase, tt.wholeWord
+		ed, _ := runKeys(t, cfg, path, ":replaceword\rx\ra\r")
+		if ed.Contents() != tt.want {
+			t.Errorf("ignorecase %v, wholeword %v: replaced to %q, want %q", tt.ignoreCase, tt.wholeWord, ed.Contents(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package editor
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Command running line in the shell. It gets a process group of its own so
+// that killCommand can stop a whole pipeline.
+func shellCommand(line string) *exec.Cmd {
+	cmd := exec.Command("/bin/sh", "-c", line)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+// Kill a command started with shellCommand and everything it started.
+func killCommand(cmd *exec.Cmd) {
+	syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
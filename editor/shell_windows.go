@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package editor
+
+import "os/exec"
+
+func shellCommand(line string) *exec.Cmd {
+	return exec.Command("cmd", "/C", line)
+}
+
+// Kill a command started with shellCommand.
+func killCommand(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,141 @@
+package editor
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A loaded word list, lower case words as keys.
+type Dictionary struct {
+	path  string
+	words map[string]bool
+}
+
+func loadDictionary(path string) (*Dictionary, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d := &Dictionary{path: path, words: make(map[string]bool)}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if w := strings.TrimSpace(s.Text()); w != "" {
+			d.words[strings.ToLower(w)] = true
+		}
+	}
+	return d, s.Err()
+}
+
+func (d *Dictionary) knows(word string) bool {
+	return d.words[strings.ToLower(word)]
+}
+
+// Call fn with the byte range of every word in s. A word is a run of letters,
+// with apostrophes allowed inside it as in "don't".
+func eachWord(s string, fn func(start, end int)) {
+	start := -1
+	for i := 0; i <= len(s); {
+		r, size := utf8.RuneError, 1
+		if i < len(s) {
+			r, size = utf8.DecodeRuneInString(s[i:])
+		}
+		inWord := unicode.IsLetter(r) || (r == '\'' && start >= 0)
+		if inWord && start < 0 {
+			start = i
+		} else if !inWord && start >= 0 {
+			end := i
+			for end > start && s[end-1] == '\'' {
+				end--
+			}
+			fn(start, end)
+			start = -1
+		}
+		i += size
+	}
+}
+
+// Whether the buffer holds prose worth spell checking, not code.
+func (ed *Editor) spellCheckable() bool {
+	return ed.cfg.spell && (ed.syntax == nil || ed.syntax.prose)
+}
+
+// Make sure the dictionary named by the config is loaded. Report a load
+// failure once rather than on every frame.
+func (ed *Editor) loadSpellDict() bool {
+	if ed.dict != nil && ed.dict.path == ed.cfg.spellFile {
+		return true
+	}
+	if ed.dictErr == ed.cfg.spellFile {
+		return false
+	}
+	d, err := loadDictionary(ed.cfg.spellFile)
+	if err != nil {
+		ed.dictErr = ed.cfg.spellFile
+		ed.fail("Can't load spell file: %v", err)
+		return false
+	}
+	ed.dict = d
+	return true
+}
+
+// Mark misspelled words in row. Other highlights, e.g. conflict sections,
+// are left alone.
+func (ed *Editor) spellCheckRow(row *Row) {
+	eachWord(row.render, func(start, end int) {
+		if ed.dict.knows(row.render[start:end]) {
+			return
+		}
+		for j := start; j < end; j++ {
+			if row.hl[j] == HL_NORMAL {
+				row.hl[j] = HL_SPELL
+			}
+		}
+	})
+}
+
+// Move the cursor to the next (dir 1) or previous (dir -1) misspelled word,
+// wrapping around the buffer.
+func (ed *Editor) jumpMisspelled(dir int) {
+	if !ed.spellCheckable() {
+		ed.setStatus("Spell checking is off for this buffer")
+		return
+	}
+	if !ed.loadSpellDict() || ed.numRows() == 0 {
+		return
+	}
+	n := ed.numRows()
+	for i := 0; i <= n; i++ {
+		if ed.scanCancelled(i) {
+			return
+		}
+		y := ((ed.cy+dir*i)%n + n) % n
+		chars := ed.row(y).chars
+		found := -1
+		eachWord(chars, func(start, end int) {
+			if ed.dict.knows(chars[start:end]) {
+				return
+			}
+			// On the cursor row only words past the cursor in the search
+			// direction count, until the search wraps back to it.
+			if i == 0 && ((dir > 0 && start <= ed.cx) || (dir < 0 && start >= ed.cx)) {
+				return
+			}
+			if i == n && ((dir > 0 && start > ed.cx) || (dir < 0 && start < ed.cx)) {
+				return
+			}
+			if (dir > 0 && found < 0) || dir < 0 {
+				found = start
+			}
+		})
+		if found >= 0 {
+			ed.cy, ed.cx = y, found
+			return
+		}
+	}
+	ed.setStatus("No misspelled words")
+}
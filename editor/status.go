@@ -0,0 +1,76 @@
+package editor
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expand the statusline setting. Tokens are:
+//
+//	%f file name      %m [+] when modified  %r [RO] when read-only
+//	%b git branch     %l line               %c column
+//	%L lines          %p percent            %y filetype
+//	%i indentation    %t time of day        %s statuscmd output
+//	%% a literal %
+//
+// and %= splits the bar into the part on the left and the part aligned to
+// the right. Anything else is kept as it is.
+func (ed *Editor) expandStatus(format string) (left, right string) {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch format[i] {
+		case 'f':
+			b.WriteString(ed.displayName())
+		case 'm':
+			if ed.dirty {
+				b.WriteString("[+]")
+			}
+		case 'r':
+			if ed.readOnly {
+				b.WriteString("[RO]")
+			}
+		case 'b':
+			b.WriteString(ed.branch)
+		case 'l':
+			b.WriteString(strconv.Itoa(ed.cy + 1))
+		case 'c':
+			b.WriteString(strconv.Itoa(ed.rx + 1))
+		case 'L':
+			b.WriteString(strconv.Itoa(ed.numRows()))
+		case 'p':
+			percent := 0
+			if ed.numRows() > 0 {
+				percent = (ed.cy + 1) * 100 / ed.numRows()
+			}
+			b.WriteString(strconv.Itoa(percent))
+		case 'y':
+			if ed.syntax != nil {
+				b.WriteString(ed.syntax.filetype)
+			}
+		case 'i':
+			b.WriteString(ed.indentInfo())
+		case 't':
+			b.WriteString(time.Now().Format("15:04"))
+		case 's':
+			b.WriteString(ed.statusHook.text)
+		case '%':
+			b.WriteByte('%')
+		case '=':
+			left = b.String()
+			b.Reset()
+		default:
+			b.WriteByte('%')
+			b.WriteByte(format[i])
+		}
+	}
+	if strings.Contains(format, "%=") {
+		return left, b.String()
+	}
+	return b.String(), ""
+}
@@ -0,0 +1,90 @@
+package editor
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Longest a status command may run before it is killed, so a hung one
+// doesn't stop the segment from ever updating again.
+const STATUS_CMD_TIMEOUT = 10 * time.Second
+
+// The statuscmd segment of the status bar. The command runs in the
+// background and its output is kept until the next run.
+type statusHook struct {
+	text string
+	// When the command was last started, and for which file.
+	ran     time.Time
+	file    string
+	running bool
+	// Whether a run was asked for while one was still going.
+	again bool
+	done  chan string
+}
+
+// Take the output of a finished status command, and start the command again
+// when its interval is up or force is set. The command gets the file name,
+// cursor line and column in EXA_FILE, EXA_LINE and EXA_COL, and its first
+// line of output is shown.
+func (ed *Editor) updateStatusHook(force bool) {
+	h := &ed.statusHook
+	select {
+	case text := <-h.done:
+		h.text, h.running = text, false
+		if interval := ed.cfg.statusInterval; interval > 0 {
+			// Nothing else may wake the editor up until it is due.
+			time.AfterFunc(interval, ed.keys.wakeUp)
+		}
+	default:
+	}
+	if ed.cfg.statusCmd == "" {
+		h.text = ""
+		return
+	}
+	due := h.ran.IsZero() || h.file != ed.filename || (ed.cfg.statusInterval > 0 && time.Since(h.ran) >= ed.cfg.statusInterval)
+	if h.running {
+		h.again = h.again || force
+		return
+	}
+	if !force && !h.again && !due {
+		return
+	}
+	if h.done == nil {
+		h.done = make(chan string, 1)
+	}
+	h.ran, h.file, h.running, h.again = time.Now(), ed.filename, true, false
+	cmd := shellCommand(ed.cfg.statusCmd)
+	cmd.Env = append(os.Environ(),
+		"EXA_FILE="+ed.filename,
+		"EXA_LINE="+strconv.Itoa(ed.cy+1),
+		"EXA_COL="+strconv.Itoa(ed.rx+1))
+	done, wake := h.done, ed.keys.wakeUp
+	go func() {
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		err := cmd.Start()
+		if err == nil {
+			deadline := time.Now().Add(STATUS_CMD_TIMEOUT)
+			err = waitCommand(cmd, func() bool { return time.Now().After(deadline) })
+		}
+		text := out.String()
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			text = text[:i]
+		}
+		text = strings.Map(func(r rune) rune {
+			// Control characters would mess up the bar.
+			if r < ' ' || r == 0x7f {
+				return -1
+			}
+			return r
+		}, strings.TrimSpace(text))
+		if text == "" && err != nil {
+			text = "statuscmd failed"
+		}
+		done <- text
+		wake()
+	}()
+}
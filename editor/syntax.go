@@ -0,0 +1,228 @@
+package editor
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Language specific settings, picked by file extension or a shebang line.
+type Syntax struct {
+	filetype string
+	// File extensions, dot included.
+	extensions []string
+	// Interpreter names as found in a "#!" first line, without version.
+	interpreters []string
+	// Prose rather than code, e.g. spell checked.
+	prose bool
+	// Pairs of brackets, opener then closer, that indent what they
+	// enclose, e.g. "{}()". Enter after an opener indents a level deeper,
+	// and a line of closers is lined up with the line of its opener.
+	// Without any, Enter keeps the indentation of the line.
+	brackets string
+	// Markers starting a comment. The rest of the line after one is taken
+	// as comment, up to the end of a block comment, see eachComment.
+	comments []string
+	// Keywords opening a block, each with the keyword closing it, e.g.
+	// "if": "fi". With matchpairs on, the partner of the keyword under the
+	// cursor is highlighted.
+	blockPairs map[string]string
+	// How files of the type are usually indented, nil to keep the
+	// configured style.
+	indent *indentStyle
+}
+
+// Indentation settings applied together, per filetype.
+type indentStyle struct {
+	expandTabs bool
+	// Columns per level when indenting with spaces, or of a tab when
+	// not. 0 keeps the configured tabstop for tabs.
+	width int
+}
+
+// Parse an indentation style: "tabs", "tabs:N" for tabs N wide or
+// "spaces:N".
+func parseIndentStyle(value string) (*indentStyle, error) {
+	kind, num := value, ""
+	if i := strings.IndexByte(value, ':'); i >= 0 {
+		kind, num = value[:i], value[i+1:]
+	}
+	width, err := strconv.Atoi(num)
+	if num == "" && kind == "tabs" {
+		width, err = 0, nil
+	}
+	if (kind != "tabs" && kind != "spaces") || err != nil || width < 0 || width > MAX_INDENT_WIDTH || (kind == "spaces" && width < 1) {
+		return nil, fmt.Errorf("bad indent style %q, want tabs, tabs:N or spaces:N", value)
+	}
+	return &indentStyle{expandTabs: kind == "spaces", width: width}, nil
+}
+
+var syntaxes = []Syntax{
+	{
+		filetype:   "text",
+		extensions: []string{".txt"},
+		prose:      true,
+	},
+	{
+		filetype:   "markdown",
+		extensions: []string{".md", ".markdown"},
+		prose:      true,
+	},
+	{
+		filetype:   "go",
+		extensions: []string{".go"},
+		brackets:   "{}()[]",
+		comments:   []string{"//", "/*"},
+		// gofmt's.
+		indent: &indentStyle{expandTabs: false},
+	},
+	{
+		filetype:   "c",
+		extensions: []string{".c", ".h", ".cpp", ".hpp", ".cc"},
+		brackets:   "{}()[]",
+		comments:   []string{"//", "/*"},
+	},
+	{
+		filetype:     "python",
+		extensions:   []string{".py"},
+		interpreters: []string{"python"},
+		comments:     []string{"#"},
+		// PEP 8.
+		indent: &indentStyle{expandTabs: true, width: 4},
+	},
+	{
+		filetype:   "yaml",
+		extensions: []string{".yaml", ".yml"},
+		comments:   []string{"#"},
+		// YAML doesn't allow tabs for indentation.
+		indent: &indentStyle{expandTabs: true, width: 2},
+	},
+	{
+		filetype:     "sh",
+		extensions:   []string{".sh", ".bash"},
+		interpreters: []string{"sh", "bash", "dash", "ksh", "zsh"},
+		comments:     []string{"#"},
+		// for and while loops are closed by the done of their do.
+		blockPairs: map[string]string{"if": "fi", "case": "esac", "do": "done"},
+	},
+	{
+		filetype:     "perl",
+		extensions:   []string{".pl", ".pm"},
+		interpreters: []string{"perl"},
+		brackets:     "{}()[]",
+		comments:     []string{"#"},
+	},
+	{
+		filetype:     "ruby",
+		extensions:   []string{".rb"},
+		interpreters: []string{"ruby"},
+		comments:     []string{"#"},
+	},
+	{
+		filetype:     "javascript",
+		extensions:   []string{".js"},
+		interpreters: []string{"node"},
+		brackets:     "{}()[]",
+		comments:     []string{"//", "/*"},
+	},
+	{
+		filetype:     "lua",
+		extensions:   []string{".lua"},
+		interpreters: []string{"lua"},
+		comments:     []string{"--"},
+		// As in sh, for and while blocks are opened by their do.
+		blockPairs: map[string]string{"function": "end", "if": "end", "do": "end", "repeat": "until"},
+	},
+}
+
+// Pick the syntax for the open file, by extension first and then by the
+// interpreter named in a shebang, and apply its indentation style. Leave it
+// nil when nothing matches. Rows read before were rendered with the tab stop
+// before and highlighted without the filetype's comments, so they are done
+// again if either changed.
+func (ed *Editor) selectSyntax() {
+	syntax, tabStop := ed.syntax, ed.cfg.tabStop
+	ed.findSyntax()
+	ed.applyIndentStyle()
+	if ed.syntax != syntax || ed.cfg.tabStop != tabStop {
+		ed.updateRows()
+	}
+}
+
+func (ed *Editor) findSyntax() {
+	ed.syntax = nil
+	ext := filepath.Ext(ed.filename)
+	for i := range syntaxes {
+		for _, e := range syntaxes[i].extensions {
+			if ext == e {
+				ed.syntax = &syntaxes[i]
+				return
+			}
+		}
+	}
+	if ed.numRows() == 0 {
+		return
+	}
+	interp := shebangInterpreter(ed.row(0).chars)
+	if interp == "" {
+		return
+	}
+	for i := range syntaxes {
+		for _, name := range syntaxes[i].interpreters {
+			if interp == name {
+				ed.syntax = &syntaxes[i]
+				return
+			}
+		}
+	}
+}
+
+// Return the interpreter of a "#!" line without path or version, e.g.
+// "python" for both "#!/usr/bin/python3.8" and "#!/usr/bin/env python3".
+func shebangInterpreter(line string) string {
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+	fields := strings.Fields(line[2:])
+	if len(fields) == 0 {
+		return ""
+	}
+	prog := filepath.Base(fields[0])
+	if prog == "env" {
+		// Skip env's own flags and variable assignments.
+		prog = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") && !strings.Contains(f, "=") {
+				prog = filepath.Base(f)
+				break
+			}
+		}
+	}
+	return strings.TrimRight(prog, "0123456789.")
+}
+
+// Go back to the configured indentation settings, then take the filetype's
+// style, from the ftindent setting or else the built-in one. Detected
+// indentation and modelines are applied on top after opening a file, and
+// :set changes them for the file being edited.
+func (ed *Editor) applyIndentStyle() {
+	base := ed.indentBase
+	ed.cfg.tabStop, ed.cfg.expandTabs, ed.cfg.shiftWidth = base.tabStop, base.expandTabs, base.shiftWidth
+	var style *indentStyle
+	if ed.syntax != nil {
+		style = ed.syntax.indent
+		if s, ok := ed.cfg.ftIndent[ed.syntax.filetype]; ok {
+			style = s
+		}
+	}
+	if style != nil {
+		ed.cfg.expandTabs = style.expandTabs
+		switch {
+		case style.expandTabs:
+			ed.cfg.shiftWidth = style.width
+		case style.width > 0:
+			ed.cfg.tabStop = style.width
+		}
+	}
+}
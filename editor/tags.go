@@ -0,0 +1,152 @@
+package editor
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Where a tag jump was made from, to return to.
+type tagPos struct {
+	filename string
+	cy, cx   int
+}
+
+// A definition found in a tags file.
+type tag struct {
+	filename string
+	// Line number, or the line itself for a search pattern address.
+	line    int
+	pattern string
+}
+
+// Find the tags file for the current file: "tags" in its directory or the
+// nearest parent that has one, like ctags -R writes at a project root.
+func (ed *Editor) findTags() string {
+	dir, err := filepath.Abs(filepath.Dir(ed.filename))
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, "tags")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Look name up in the ctags file at path. The file is read on every lookup
+// so it's never stale after ctags runs again.
+func lookupTag(path, name string) (tag, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return tag{}, false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	prefix := name + "\t"
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		// name<TAB>file<TAB>address;"<TAB>extension fields
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		t := tag{filename: fields[1]}
+		if !filepath.IsAbs(t.filename) {
+			t.filename = filepath.Join(filepath.Dir(path), t.filename)
+		}
+		addr := fields[2]
+		if i := strings.Index(addr, ";\""); i >= 0 {
+			addr = addr[:i]
+		}
+		if n, err := strconv.Atoi(addr); err == nil {
+			t.line = n
+		} else if len(addr) >= 2 && (addr[0] == '/' || addr[0] == '?') {
+			t.pattern = tagPattern(addr[1 : len(addr)-1])
+		}
+		return t, true
+	}
+	return tag{}, false
+}
+
+// The line a ctags search pattern such as "^func main() {$" stands for.
+// ctags escapes only the delimiters and backslashes.
+func tagPattern(p string) string {
+	p = strings.TrimPrefix(p, "^")
+	p = strings.TrimSuffix(p, "$")
+	r := strings.NewReplacer(`\/`, "/", `\?`, "?", `\\`, `\`)
+	return r.Replace(p)
+}
+
+// Jump to the definition of name, or of the identifier under the cursor
+// when name is "", remembering where the jump came from.
+func (ed *Editor) jumpToTag(name string) {
+	if name == "" && ed.numRows() > 0 {
+		name = ed.wordAt(ed.row(ed.cy).chars, ed.cx)
+	}
+	if name == "" {
+		ed.setStatus("No identifier under the cursor")
+		return
+	}
+	path := ed.findTags()
+	if path == "" {
+		ed.setStatus("No tags file")
+		return
+	}
+	t, ok := lookupTag(path, name)
+	if !ok {
+		ed.setStatus("Tag not found: %s", name)
+		return
+	}
+	from := tagPos{ed.filename, ed.cy, ed.cx}
+	if !ed.sameFile(t.filename) && !ed.edit(t.filename, 1, 1) {
+		return
+	}
+	ed.tagStack = append(ed.tagStack, from)
+	line := t.line
+	if t.pattern != "" {
+		for i := 0; i < ed.numRows(); i++ {
+			if ed.row(i).chars == t.pattern {
+				line = i + 1
+				break
+			}
+		}
+	}
+	if line > 0 {
+		ed.jumpTo(line, 1)
+	}
+}
+
+// Go back to where the last tag jump was made from.
+func (ed *Editor) popTag() {
+	if len(ed.tagStack) == 0 {
+		ed.setStatus("Tag stack is empty")
+		return
+	}
+	from := ed.tagStack[len(ed.tagStack)-1]
+	if !ed.sameFile(from.filename) && !ed.edit(from.filename, 1, 1) {
+		return
+	}
+	ed.tagStack = ed.tagStack[:len(ed.tagStack)-1]
+	ed.jumpTo(from.cy+1, from.cx+1)
+}
+
+// Whether filename is the file in the buffer.
+func (ed *Editor) sameFile(filename string) bool {
+	a, err1 := os.Stat(filename)
+	b, err2 := os.Stat(ed.filename)
+	return err1 == nil && err2 == nil && os.SameFile(a, b)
+}
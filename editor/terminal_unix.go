@@ -0,0 +1,42 @@
+//go:build !windows
+// +build !windows
+
+package editor
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/term"
+)
+
+// Put the terminal in raw mode. The returned function restores it.
+func setupTerminal() (restore func(), err error) {
+	oldState, err := term.MakeRaw(0)
+	if err != nil {
+		return nil, err
+	}
+	return func() { term.Restore(0, oldState) }, nil
+}
+
+// Size of the terminal in columns and rows.
+func terminalSize() (width, height int, err error) {
+	return term.GetSize(0)
+}
+
+// Channel receiving a value whenever the terminal is resized.
+func resizeSignal() <-chan os.Signal {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGWINCH)
+	return ch
+}
+
+// Channel receiving a value when the editor is asked to stop: killed, or
+// the terminal hung up. In raw mode Ctrl-C is a key, so an interrupt comes
+// from elsewhere too.
+func stopSignal() <-chan os.Signal {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	return ch
+}
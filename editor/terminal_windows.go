@@ -0,0 +1,68 @@
+//go:build windows
+// +build windows
+
+package editor
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/term"
+)
+
+// Put the console in raw mode and turn on virtual terminal processing, so it
+// understands the same escape sequences as a Unix terminal both ways. The
+// returned function restores it.
+func setupTerminal() (restore func(), err error) {
+	in := windows.Handle(os.Stdin.Fd())
+	out := windows.Handle(os.Stdout.Fd())
+
+	oldState, err := term.MakeRaw(int(in))
+	if err != nil {
+		return nil, err
+	}
+	var inMode, outMode uint32
+	if err := windows.GetConsoleMode(in, &inMode); err != nil {
+		term.Restore(int(in), oldState)
+		return nil, err
+	}
+	if err := windows.GetConsoleMode(out, &outMode); err != nil {
+		term.Restore(int(in), oldState)
+		return nil, err
+	}
+	// Arrow and page keys arrive as <esc>[ sequences with VT input.
+	if err := windows.SetConsoleMode(in, inMode|windows.ENABLE_VIRTUAL_TERMINAL_INPUT); err != nil {
+		term.Restore(int(in), oldState)
+		return nil, err
+	}
+	if err := windows.SetConsoleMode(out, outMode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING); err != nil {
+		term.Restore(int(in), oldState)
+		return nil, err
+	}
+	return func() {
+		windows.SetConsoleMode(out, outMode)
+		term.Restore(int(in), oldState)
+	}, nil
+}
+
+// Size of the console window in columns and rows. On Windows it is a
+// property of the output screen buffer, not of the input handle.
+func terminalSize() (width, height int, err error) {
+	return term.GetSize(int(os.Stdout.Fd()))
+}
+
+// The console sends no resize signal. The size is still picked up on the
+// next redraw, so nil, which never fires, will do.
+func resizeSignal() <-chan os.Signal {
+	return nil
+}
+
+// Channel receiving a value when the console is closed or the editor
+// interrupted.
+func stopSignal() <-chan os.Signal {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	return ch
+}
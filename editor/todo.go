@@ -0,0 +1,157 @@
+package editor
+
+import "strings"
+
+// Index in chars where a comment starts, -1 if the line has none or there
+// is no syntax. Quotes aren't understood, so a marker inside a string counts
+// too.
+func (s *Syntax) commentStart(chars string) int {
+	if s == nil {
+		return -1
+	}
+	start := -1
+	for _, c := range s.comments {
+		if i := strings.Index(chars, c); i >= 0 && (start < 0 || i < start) {
+			start = i
+		}
+	}
+	return start
+}
+
+// Marker ending a block comment, "" for a comment running to the end of
+// the line.
+func commentEnd(marker string) string {
+	if marker == "/*" {
+		return "*/"
+	}
+	return ""
+}
+
+// Call fn with each range in chars that is comment, markers included. in
+// is whether the line starts inside a block comment, see ST_COMMENT, which
+// then runs up to its closing marker. Return whether the line ends inside
+// one.
+func (s *Syntax) eachComment(chars string, in bool, fn func(from, to int)) bool {
+	if s == nil {
+		return false
+	}
+	i := 0
+	if in {
+		end := ""
+		for _, c := range s.comments {
+			if end = commentEnd(c); end != "" {
+				break
+			}
+		}
+		j := strings.Index(chars, end)
+		if end == "" || j < 0 {
+			fn(0, len(chars))
+			return end != ""
+		}
+		fn(0, j+len(end))
+		i = j + len(end)
+	}
+	for i < len(chars) {
+		start := s.commentStart(chars[i:])
+		if start < 0 {
+			return false
+		}
+		start += i
+		marker := ""
+		for _, c := range s.comments {
+			if strings.HasPrefix(chars[start:], c) && len(c) > len(marker) {
+				marker = c
+			}
+		}
+		end := commentEnd(marker)
+		j := -1
+		if end != "" {
+			j = strings.Index(chars[start+len(marker):], end)
+		}
+		if j < 0 {
+			fn(start, len(chars))
+			return end != ""
+		}
+		j += start + len(marker) + len(end)
+		fn(start, j)
+		i = j
+	}
+	return false
+}
+
+// Mark the comments of row in hl, where nothing else is marked, e.g. not
+// over conflict sections.
+func (ed *Editor) markComments(row *Row) {
+	ed.syntax.eachComment(row.chars, row.hlStart&ST_COMMENT != 0, func(from, to int) {
+		for j := row.cxToRx(from, ed.cfg.tabStop); j < row.cxToRx(to, ed.cfg.tabStop); j++ {
+			if row.hl[j] == HL_NORMAL {
+				row.hl[j] = HL_COMMENT
+			}
+		}
+	})
+}
+
+// Call fn with the range in the chars of row of every TODO marker in a
+// comment. Only whole words count, e.g. not the TODO in TODOS.
+func (ed *Editor) eachTodo(row *Row, fn func(start, end int)) {
+	chars := row.chars
+	isWord := func(i int) bool {
+		return i >= 0 && i < len(chars) && !ed.isSeparator(chars[i])
+	}
+	ed.syntax.eachComment(chars, row.hlStart&ST_COMMENT != 0, func(from, to int) {
+		for _, marker := range ed.cfg.todoMarkers {
+			for i := from; ; {
+				j := strings.Index(chars[i:to], marker)
+				if j < 0 {
+					break
+				}
+				start, end := i+j, i+j+len(marker)
+				if !isWord(start-1) && !isWord(end) {
+					fn(start, end)
+				}
+				i = end
+			}
+		}
+	})
+}
+
+// Mark the TODO markers of row in hl.
+func (ed *Editor) markTodos(row *Row) {
+	ed.eachTodo(row, func(start, end int) {
+		from, to := row.cxToRx(start, ed.cfg.tabStop), row.cxToRx(end, ed.cfg.tabStop)
+		for j := from; j < to; j++ {
+			row.hl[j] = HL_TODO
+		}
+	})
+}
+
+// Move the cursor to the next (dir 1) or previous (dir -1) TODO marker,
+// wrapping around the buffer.
+func (ed *Editor) jumpTodo(dir int) {
+	n := ed.numRows()
+	for i := 0; i <= n && n > 0; i++ {
+		if ed.scanCancelled(i) {
+			return
+		}
+		y := ((ed.cy+dir*i)%n + n) % n
+		found := -1
+		ed.eachTodo(ed.row(y), func(start, end int) {
+			// As in jumpMisspelled, the cursor row only counts past the
+			// cursor until the search wraps back to it.
+			if i == 0 && ((dir > 0 && start <= ed.cx) || (dir < 0 && start >= ed.cx)) {
+				return
+			}
+			if i == n && ((dir > 0 && start > ed.cx) || (dir < 0 && start < ed.cx)) {
+				return
+			}
+			if found < 0 || (dir > 0 && start < found) || (dir < 0 && start > found) {
+				found = start
+			}
+		})
+		if found >= 0 {
+			ed.cy, ed.cx = y, found
+			return
+		}
+	}
+	ed.setStatus("No TODO markers")
+}
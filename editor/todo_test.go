@@ -0,0 +1,88 @@
+package editor
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEachTodo(t *testing.T) {
+	tests := []struct {
+		line string
+		// Whether the line starts inside a block comment.
+		in bool
+		// Where the markers counted start.
+		want []int
+	}{
+		{"x := 1 // TODO: y", false, []int{10}},
+		{"TODO := 1", false, nil},
+		{"// TODOS are not TODO", false, []int{17}},
+		{"/* TODO */ TODO()", false, []int{3}},
+		{"/* TODO */ f() /* FIXME */", false, []int{3, 18}},
+		{"/* TODO: more", false, []int{3}},
+		{"   TODO: the end */ TODO()", true, []int{3}},
+		{"   TODO: the end */ TODO()", false, nil},
+		{"   TODO: all of it", true, []int{3}},
+		{"end */ TODO /* XXX */ TODO", true, []int{15}},
+		{"/* a */ TODO() // XXX", false, []int{18}},
+	}
+	ed := New(DefaultConfig(), strings.NewReader(""), nil)
+	ed.filename = "a.go"
+	ed.selectSyntax()
+	for _, tt := range tests {
+		var got []int
+		row := &Row{chars: tt.line}
+		if tt.in {
+			row.hlStart = ST_COMMENT
+		}
+		ed.eachTodo(row, func(start, end int) { got = append(got, start) })
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q, in a comment %v: markers at %v, want %v", tt.line, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJumpTodo(t *testing.T) {
+	path := writeTemp(t, "a.go", "/* TODO */ TODO()\nf()\n// FIXME\n")
+	ed, _ := runKeys(t, DefaultConfig(), path, "")
+	// The TODO after the comment is code.
+	for _, want := range [][2]int{{0, 3}, {2, 3}, {0, 3}} {
+		ed.jumpTodo(1)
+		if ed.cy != want[0] || ed.cx != want[1] {
+			t.Errorf("jumped to %d:%d, want %d:%d", ed.cy, ed.cx, want[0], want[1])
+		}
+	}
+}
+
+func TestTodoInBlockComment(t *testing.T) {
+	path := writeTemp(t, "a.go", "f()\nTODO: g\nh()\n")
+	tests := []struct {
+		keys string
+		// Whether the TODO on the second row is marked and jumped to.
+		todo bool
+	}{
+		{"", false},
+		{insertKey + "/*", true},
+		{insertKey + "/*\x7f", false},
+		{insertKey + "/* */", false},
+		{insertKey + "/*\x1b[B\x1b[B */", true},
+		// Closed after it on its own row.
+		{insertKey + "/*\x1b[B\x1b[F */", true},
+		{insertKey + "/*\x1b[B\x1b[F */\x1b[A\x1b[H\x1b[3~", false},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.autoClose = false
+		ed, _ := runKeys(t, cfg, path, tt.keys)
+		ed.refresh()
+		hl := ed.rows[1].hl
+		if marked := len(hl) > 0 && hl[0] == HL_TODO; marked != tt.todo {
+			t.Errorf("%q: TODO marked %v, want %v", tt.keys, marked, tt.todo)
+		}
+		ed.cy, ed.cx = 0, 0
+		ed.jumpTodo(1)
+		if found := ed.cy == 1 && ed.cx == 0; found != tt.todo {
+			t.Errorf("%q: jumped to %d:%d, status %q", tt.keys, ed.cy, ed.cx, ed.statusmsg)
+		}
+	}
+}
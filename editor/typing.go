@@ -0,0 +1,51 @@
+package editor
+
+import (
+	"unicode/utf8"
+)
+
+// Insert starts typing text, keys other than printable characters keep
+// their bindings meanwhile. Insert again switches between inserting and
+// overwriting, Escape stops typing.
+func (ed *Editor) toggleInsert() {
+	if !ed.typing {
+		ed.typing, ed.overwrite = true, false
+		return
+	}
+	ed.overwrite = !ed.overwrite
+}
+
+// Whether ch is text to type rather than a key to look up in the keymap.
+func isTypedChar(ch EdKey) bool {
+	return ch >= ' ' && ch < 256 && ch != 127
+}
+
+// Type r at the cursor. Overwriting replaces the character under the
+// cursor, at the end of the line it appends like inserting.
+func (ed *Editor) insertChar(r rune) {
+	if !ed.checkWritable() {
+		return
+	}
+	if ed.numRows() == 0 {
+		ed.insertRow(0, "")
+	}
+	chars, s := ed.rows[ed.cy].chars, string(r)
+	end := ed.cx
+	if ed.overwrite && end < len(chars) {
+		_, size := utf8.DecodeRuneInString(chars[end:])
+		end += size
+	}
+	ed.rowSetChars(ed.cy, chars[:ed.cx]+s+chars[end:])
+	ed.cx += len(s)
+}
+
+// Status bar marker of typing mode, "" when not typing.
+func (ed *Editor) typingMarker() string {
+	switch {
+	case !ed.typing:
+		return ""
+	case ed.overwrite:
+		return " [OVR]"
+	}
+	return " [INS]"
+}
@@ -0,0 +1,47 @@
+package editor
+
+import "testing"
+
+const insertKey = "\x1b[2~"
+
+func TestTyping(t *testing.T) {
+	tests := []struct {
+		name, text, keys, want string
+	}{
+		{"inserts", "", insertKey + "ab1", "ab1\n"},
+		{"inserts before the cursor", "hello\n", "\x1b[C" + insertKey + "XY", "hXYello\n"},
+		{"overwrites", "hello\n", insertKey + insertKey + "XY", "XYllo\n"},
+		{"overwrite appends at the end", "hi\n", "\x1b[C" + insertKey + insertKey + "XYZ", "hXYZ\n"},
+		{"overwrites a whole character", "héllo\n", "\x1b[C" + insertKey + insertKey + "e", "hello\n"},
+		{"types multibyte characters", "", insertKey + "né", "né\n"},
+		{"back to inserting", "ab\n", insertKey + insertKey + insertKey + "X", "Xab\n"},
+		{"Escape stops typing", "", insertKey + "a\x1bxy", "a\n"},
+		{"keys keep their bindings", "", insertKey + "a\rb", "a\nb\n"},
+		{"text stays commands without Insert", "ab\n", "xy", "ab\n"},
+	}
+	for _, tt := range tests {
+		name := ""
+		if tt.text != "" {
+			name = writeTemp(t, "a.txt", tt.text)
+		}
+		ed, _ := runKeys(t, DefaultConfig(), name, tt.keys)
+		if got := ed.Contents(); got != tt.want {
+			t.Errorf("%s: buffer %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTypingMarker(t *testing.T) {
+	ed, _ := runKeys(t, DefaultConfig(), "", insertKey)
+	if got := ed.typingMarker(); got != " [INS]" {
+		t.Errorf("marker %q after Insert, want [INS]", got)
+	}
+	ed, _ = runKeys(t, DefaultConfig(), "", insertKey+insertKey)
+	if got := ed.typingMarker(); got != " [OVR]" {
+		t.Errorf("marker %q after Insert twice, want [OVR]", got)
+	}
+	ed, _ = runKeys(t, DefaultConfig(), "", insertKey+"\x1b")
+	if got := ed.typingMarker(); got != "" {
+		t.Errorf("marker %q after Escape, want none", got)
+	}
+}
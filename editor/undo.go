@@ -0,0 +1,209 @@
+package editor
+
+// A row's text and line ending, as undo keeps them.
+type undoLine struct {
+	chars string
+	crlf  bool
+}
+
+// One change to the buffer: the n rows from at replaced the rows in old.
+// Undoing it puts old back.
+type undoChange struct {
+	at, n int
+	old   []undoLine
+}
+
+// The changes made by one key or command, undone together, the cursor and
+// missing final newline from before them, and the cursor after.
+type undoGroup struct {
+	changes []undoChange
+	// The state of the buffer after the group, see undoState.
+	id     int
+	cy, cx int
+	ay, ax int
+	noEOL  bool
+	// Characters typed since, with no other key between, go in this group
+	// too.
+	open bool
+}
+
+func linesOf(rows []Row) []undoLine {
+	lines := make([]undoLine, len(rows))
+	for i := range rows {
+		lines[i] = undoLine{rows[i].chars, rows[i].crlf}
+	}
+	return lines
+}
+
+// Add c to the group. Another change to the row changed last, as when
+// typing along a line, needs nothing new to be undone.
+func (g *undoGroup) add(c undoChange) {
+	if n := len(g.changes); n > 0 {
+		last := g.changes[n-1]
+		if last.at == c.at && last.n == 1 && len(last.old) == 1 && c.n == 1 && len(c.old) == 1 {
+			return
+		}
+	}
+	g.changes = append(g.changes, c)
+}
+
+// Start the group of changes for the key about to be handled.
+func (ed *Editor) beginUndo() {
+	ed.undoing = &undoGroup{cy: ed.cy, cx: ed.cx, noEOL: ed.noEOL}
+}
+
+// Put the changes since beginUndo on the undo stack as one group. With
+// typed, characters typed, they join the group of those typed before.
+func (ed *Editor) endUndo(typed bool) {
+	g := ed.undoing
+	ed.undoing = nil
+	n := len(ed.undoStack)
+	if g == nil || len(g.changes) == 0 {
+		if !typed && n > 0 {
+			ed.undoStack[n-1].open = false
+		}
+		return
+	}
+	// The saved state stays as it was saved.
+	if typed && n > 0 && ed.undoStack[n-1].open && ed.undoStack[n-1].id != ed.savedUndo {
+		top := &ed.undoStack[n-1]
+		for _, c := range g.changes {
+			top.add(c)
+		}
+		top.ay, top.ax = ed.cy, ed.cx
+		return
+	}
+	g.ay, g.ax = ed.cy, ed.cx
+	g.open = typed
+	ed.undoSeq++
+	g.id = ed.undoSeq
+	ed.undoStack = append(ed.undoStack, *g)
+	ed.dropUndo()
+}
+
+// Drop the oldest groups past undolevels. The state before the first group
+// left is the one undo gets back to now, and ends up modified unless it is
+// the one saved.
+func (ed *Editor) dropUndo() {
+	drop := len(ed.undoStack) - ed.cfg.undoLevels
+	if drop <= 0 {
+		return
+	}
+	ed.undoBase = ed.undoStack[drop-1].id
+	n := copy(ed.undoStack, ed.undoStack[drop:])
+	for i := n; i < len(ed.undoStack); i++ {
+		// Let go of the lines.
+		ed.undoStack[i] = undoGroup{}
+	}
+	ed.undoStack = ed.undoStack[:n]
+}
+
+// Which state of the buffer undo and redo have it in, the id of the last
+// group undo would take back, or with none that before the groups dropped,
+// 0 for the text read. The buffer is unmodified when it's in the state it
+// was saved in.
+func (ed *Editor) undoState() int {
+	if n := len(ed.undoStack); n > 0 {
+		return ed.undoStack[n-1].id
+	}
+	return ed.undoBase
+}
+
+// End the group of changes here, those made later in the same command are
+// undone on their own.
+func (ed *Editor) breakUndo() {
+	ed.endUndo(false)
+	ed.beginUndo()
+}
+
+// Note that the n rows from at are about to be replaced with count others,
+// for undo.
+func (ed *Editor) recordRows(at, n, count int) {
+	ed.recordChange(at, linesOf(ed.rows[at:at+n]), count)
+}
+
+// Note that the n rows from at replaced the rows in old.
+func (ed *Editor) recordChange(at int, old []undoLine, n int) {
+	if ed.undoing == nil {
+		// A change not made by a key, e.g. from a batch command.
+		ed.beginUndo()
+	}
+	ed.undoing.add(undoChange{at, n, old})
+	ed.redoStack = nil
+}
+
+// Forget all changes, the buffer is another one now, as on disk.
+func (ed *Editor) clearUndo() {
+	ed.undoStack, ed.redoStack, ed.undoing = nil, nil, nil
+	ed.undoSeq, ed.savedUndo, ed.undoBase = 0, 0, 0
+}
+
+// Take back the last group of changes, putting the cursor back where it
+// was before them.
+func (ed *Editor) undo() {
+	if !ed.checkWritable() {
+		return
+	}
+	n := len(ed.undoStack)
+	if n == 0 {
+		ed.setStatus("Nothing to undo")
+		return
+	}
+	g := ed.undoStack[n-1]
+	ed.undoStack = ed.undoStack[:n-1]
+	ed.redoStack = append(ed.redoStack, ed.applyUndo(g))
+	ed.dirty = ed.undoState() != ed.savedUndo
+	ed.setStatus("Undone, %d more to undo", len(ed.undoStack))
+}
+
+// Make the last group of changes undone again.
+func (ed *Editor) redo() {
+	if !ed.checkWritable() {
+		return
+	}
+	n := len(ed.redoStack)
+	if n == 0 {
+		ed.setStatus("Nothing to redo")
+		return
+	}
+	g := ed.redoStack[n-1]
+	ed.redoStack = ed.redoStack[:n-1]
+	ed.undoStack = append(ed.undoStack, ed.applyUndo(g))
+	ed.dirty = ed.undoState() != ed.savedUndo
+	ed.setStatus("Redone, %d more to redo", len(ed.redoStack))
+}
+
+// Put back what the changes of g replaced, last change first, and return
+// the group doing the opposite, which brings back the same state and puts
+// the cursor where g had it after.
+func (ed *Editor) applyUndo(g undoGroup) undoGroup {
+	back := undoGroup{id: g.id, cy: g.ay, cx: g.ax, ay: g.cy, ax: g.cx, noEOL: ed.noEOL}
+	for i := len(g.changes) - 1; i >= 0; i-- {
+		c := g.changes[i]
+		back.changes = append(back.changes, undoChange{c.at, len(c.old), linesOf(ed.rows[c.at : c.at+c.n])})
+		ed.spliceRows(c.at, c.n, c.old)
+	}
+	ed.noEOL = g.noEOL
+	ed.cursors = nil
+	ed.cy, ed.cx = g.cy, g.cx
+	ed.clampCursor()
+	return back
+}
+
+// Replace the n rows from at with lines.
+func (ed *Editor) spliceRows(at, n int, lines []undoLine) {
+	rows := make([]Row, len(lines))
+	state := ST_NONE
+	if at > 0 {
+		state = ed.rows[at-1].hlState
+	}
+	for i, line := range lines {
+		rows[i] = Row{chars: line.chars, crlf: line.crlf}
+		rows[i].update(ed.cfg.tabStop)
+		state = rows[i].highlight(state, ed.syntax)
+	}
+	ed.rows = append(ed.rows[:at:at], append(rows, ed.rows[at+n:]...)...)
+	if end := at + len(rows); end < len(ed.rows) {
+		ed.updateSyntax(end)
+	}
+}
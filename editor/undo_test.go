@@ -0,0 +1,130 @@
+package editor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUndo(t *testing.T) {
+	tests := []struct {
+		name, text, keys, want string
+		cy, cx                 int
+	}{
+		{"typed run at once", "ab\n", insertKey + "xyz\x1bu", "ab\n", 0, 0},
+		{"typing after a move", "ab\n", insertKey + "x\x1b[Cy\x1bu", "xab\n", 0, 2},
+		{"split line", "ab\n", "\x1b[C\ru", "ab\n", 0, 1},
+		{"joined lines", "ab\ncd\n", "\x1b[B\x7fu", "ab\ncd\n", 1, 0},
+		{"two changes", "ab\n", "\x1b[C\r\ruu", "ab\n", 0, 1},
+		{"one of two", "ab\n", "\x1b[C\r\ru", "a\nb\n", 1, 0},
+		{"nothing left", "ab\n", "\x1b[C\ruuu", "ab\n", 0, 1},
+		{"redo", "ab\n", "\x1b[C\ru\x12", "a\nb\n", 1, 0},
+		{"redo back after the change", "ab\n", "\x1b[C\ru\x1b[D\x12", "a\nb\n", 1, 0},
+		{"redo all", "ab\n", "\x1b[C\r\ruu\x12\x12\x12", "a\n\nb\n", 2, 0},
+		{"change drops redo", "ab\n", "\x1b[C\ru" + insertKey + "z\x1b\x12", "azb\n", 0, 2},
+		{"line endings", "ab\ncd\n", ":fixeol crlf\ru", "ab\ncd\n", 0, 0},
+		{"repeat", "abc\n", "\x1b[F\x7f..u", "a\n", 0, 1},
+	}
+	for _, tt := range tests {
+		ed, _ := runKeys(t, DefaultConfig(), writeTemp(t, "a.txt", tt.text), tt.keys)
+		if got := ed.Contents(); got != tt.want || ed.cy != tt.cy || ed.cx != tt.cx {
+			t.Errorf("%s: %q with the cursor at %d:%d, want %q at %d:%d", tt.name, got, ed.cy, ed.cx, tt.want, tt.cy, tt.cx)
+		}
+	}
+}
+
+// Point the config directory at a fresh one for the test.
+func tempConfigDir(t *testing.T) {
+	old, had := os.LookupEnv("XDG_CONFIG_HOME")
+	os.Setenv("XDG_CONFIG_HOME", filepath.Dir(writeTemp(t, "config", "")))
+	t.Cleanup(func() {
+		if had {
+			os.Setenv("XDG_CONFIG_HOME", old)
+		} else {
+			os.Unsetenv("XDG_CONFIG_HOME")
+		}
+	})
+}
+
+func TestUndoFile(t *testing.T) {
+	tempConfigDir(t)
+	cfg := DefaultConfig()
+	cfg.undoFile = true
+	path := writeTemp(t, "a.txt", "one\n")
+	runKeys(t, cfg, path, insertKey+"x\x1b:w\r\x1b[F\r:w\r")
+
+	// Undone into the last session, one change at a time.
+	ed, _ := runKeys(t, cfg, path, "u")
+	if got := ed.Contents(); got != "xone\n" || !ed.Dirty() {
+		t.Errorf("after undo %q, dirty %v, want %q, dirty", got, ed.Dirty(), "xone\n")
+	}
+	ed, _ = runKeys(t, cfg, path, "uu")
+	if got := ed.Contents(); got != "one\n" {
+		t.Errorf("after undoing twice %q, want %q", got, "one\n")
+	}
+
+	// Only kept when asked for.
+	off := DefaultConfig()
+	if ed, _ = runKeys(t, off, path, "u"); ed.Contents() != "xone\n\n" {
+		t.Errorf("undofile off: %q after undo", ed.Contents())
+	}
+
+	// History saved for other text is dropped, as is history that doesn't
+	// parse or fit the file.
+	if err := ioutil.WriteFile(path, []byte("two\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ed, _ = runKeys(t, off, path, "")
+	undoPath, hash := ed.undoPath(), ed.contentsHash()
+	for _, saved := range []string{
+		"",
+		"garbage\n",
+		fmt.Sprintf("%x\ngroup 0 0 0 0 false 1\nchange 1 1 0\n", hash),
+		fmt.Sprintf("%x\ngroup 0 0 0 0 false 1\nchange 0 1 2\n0a\n", hash),
+	} {
+		if saved != "" {
+			if err := ioutil.WriteFile(undoPath, []byte(saved), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		ed, _ = runKeys(t, cfg, path, "u")
+		if len(ed.undoStack) != 0 || ed.Dirty() {
+			t.Errorf("history %q: %d groups, dirty %v after undo", saved, len(ed.undoStack), ed.Dirty())
+		}
+	}
+}
+
+func TestUndoLevels(t *testing.T) {
+	// Three groups, each a line split off.
+	edits := "\x1b[C\r\x1b[C\r\x1b[C\r"
+	tests := []struct {
+		name   string
+		levels int
+		keys   string
+		want   string
+		dirty  bool
+	}{
+		{"all kept", 3, edits + "uuu", "abcd\n", false},
+		{"oldest dropped", 2, edits + "uuu", "a\nbcd\n", true},
+		{"none kept", 0, edits + "u", "a\nb\nc\nd\n", true},
+		{"redone to the save", 2, edits + ":w\ruu\x12\x12", "a\nb\nc\nd\n", false},
+		// The state saved is still the one before what's kept.
+		{"saved before the drop", 2, "\x1b[C\r:w\r\x1b[C\r\x1b[C\ruu", "a\nbcd\n", false},
+		{"saved at the boundary", 2, "\x1b[C\r:w\r\x1b[C\r\x1b[C\ruuu", "a\nbcd\n", false},
+		{"saved in the kept ones", 2, "\x1b[C\r\x1b[C\r:w\r\x1b[C\ruu", "a\nbcd\n", true},
+		{"back at the save", 2, "\x1b[C\r\x1b[C\r:w\r\x1b[C\ru", "a\nb\ncd\n", false},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.undoLevels = tt.levels
+		ed, _ := runKeys(t, cfg, writeTemp(t, "a.txt", "abcd\n"), tt.keys)
+		if got := ed.Contents(); got != tt.want || ed.Dirty() != tt.dirty {
+			t.Errorf("%s: %q, dirty %v, want %q, dirty %v", tt.name, got, ed.Dirty(), tt.want, tt.dirty)
+		}
+		if len(ed.undoStack) > tt.levels {
+			t.Errorf("%s: %d groups kept, want at most %d", tt.name, len(ed.undoStack), tt.levels)
+		}
+	}
+}
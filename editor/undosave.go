@@ -0,0 +1,139 @@
+package editor
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Location of the undo history saved for the file, next to the config file
+// and named for a hash of the file's absolute path.
+func (ed *Editor) undoPath() string {
+	dir, err := os.UserConfigDir()
+	key := ed.positionKey()
+	if err != nil || key == "" {
+		return ""
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return filepath.Join(dir, "exa", "undo", fmt.Sprintf("%x", h.Sum64()))
+}
+
+// Hash of the buffer as it is written, to tell whether the history saved
+// with it still fits the file.
+func (ed *Editor) contentsHash() uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(ed.Contents()))
+	return h.Sum64()
+}
+
+// Keep the undo history with the file just saved, for undoing into it the
+// next time it's opened. The file holds the hash of the text saved, then
+// each group, oldest first, as "group cy cx ay ax noeol changes" with the
+// cursor before and after, each change as "change at n lines" and the
+// lines it replaced, each led by 1 for a CRLF ending or 0. With undofile
+// off this does nothing, and failing to is not worth a message.
+func (ed *Editor) saveUndo() {
+	path := ed.undoPath()
+	if !ed.cfg.undoFile || path == "" || ed.lazy != nil || ed.partial {
+		return
+	}
+	if len(ed.undoStack) == 0 {
+		os.Remove(path)
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%x\n", ed.contentsHash())
+	for _, g := range ed.undoStack {
+		fmt.Fprintf(&b, "group %d %d %d %d %t %d\n", g.cy, g.cx, g.ay, g.ax, g.noEOL, len(g.changes))
+		for _, c := range g.changes {
+			fmt.Fprintf(&b, "change %d %d %d\n", c.at, c.n, len(c.old))
+			for _, line := range c.old {
+				if line.crlf {
+					b.WriteByte('1')
+				} else {
+					b.WriteByte('0')
+				}
+				b.WriteString(line.chars)
+				b.WriteByte('\n')
+			}
+		}
+	}
+	if os.MkdirAll(filepath.Dir(path), 0700) == nil {
+		writeFileAtomic(path, []byte(b.String()), true)
+	}
+}
+
+// Take up the undo history saved with the file, if its text is still what
+// was saved. History that doesn't parse or saved for other text is ignored.
+func (ed *Editor) restoreUndo() {
+	path := ed.undoPath()
+	if !ed.cfg.undoFile || path == "" || ed.lazy != nil || ed.partial {
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	line := func() string {
+		s, err := r.ReadString('\n')
+		if err != nil {
+			// Cut short, parsing what's left fails.
+			return "\x00"
+		}
+		return strings.TrimSuffix(s, "\n")
+	}
+	var hash uint64
+	if _, err := fmt.Sscanf(line(), "%x", &hash); err != nil || hash != ed.contentsHash() {
+		return
+	}
+	var groups []undoGroup
+	for {
+		s, err := r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		var g undoGroup
+		var n int
+		if _, err := fmt.Sscanf(strings.TrimSuffix(s, "\n"), "group %d %d %d %d %t %d", &g.cy, &g.cx, &g.ay, &g.ax, &g.noEOL, &n); err != nil {
+			return
+		}
+		for i := 0; i < n; i++ {
+			var c undoChange
+			var lines int
+			if _, err := fmt.Sscanf(line(), "change %d %d %d", &c.at, &c.n, &lines); err != nil || c.at < 0 || c.n < 0 {
+				return
+			}
+			for j := 0; j < lines; j++ {
+				s := line()
+				if s == "" || (s[0] != '0' && s[0] != '1') {
+					return
+				}
+				c.old = append(c.old, undoLine{s[1:], s[0] == '1'})
+			}
+			g.changes = append(g.changes, c)
+		}
+		g.id = len(groups) + 1
+		groups = append(groups, g)
+	}
+	// Undone the way undo would, every change has to fit the rows there
+	// are then.
+	rows := len(ed.rows)
+	for i := len(groups) - 1; i >= 0; i-- {
+		for j := len(groups[i].changes) - 1; j >= 0; j-- {
+			c := groups[i].changes[j]
+			if c.at+c.n > rows {
+				return
+			}
+			rows += len(c.old) - c.n
+		}
+	}
+	// The file is in the state after the last group.
+	ed.undoStack, ed.undoSeq, ed.savedUndo = groups, len(groups), len(groups)
+	ed.dropUndo()
+}
@@ -0,0 +1,221 @@
+package editor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Whether c separates words, as opposed to being part of one. This is the
+// one definition of a word used by word motions and deletion, completion,
+// tag lookup, TODO markers and replaceword.
+func (ed *Editor) isSeparator(c byte) bool {
+	return c == ' ' || c == '\t' || strings.IndexByte(ed.separators(), c) >= 0
+}
+
+// Characters that end a word besides whitespace: the separators setting,
+// unless the filetype has its own.
+func (ed *Editor) separators() string {
+	if ed.syntax != nil {
+		if seps, ok := ed.cfg.ftSeparators[ed.syntax.filetype]; ok {
+			return seps
+		}
+	}
+	return ed.cfg.separators
+}
+
+// The word around index i of s, "" if there is none.
+func (ed *Editor) wordAt(s string, i int) string {
+	start, end := i, i
+	for start > 0 && !ed.isSeparator(s[start-1]) {
+		start--
+	}
+	for end < len(s) && !ed.isSeparator(s[end]) {
+		end++
+	}
+	return s[start:end]
+}
+
+// Start of the word before index i of s: skip separators backwards, then
+// the word itself.
+func (ed *Editor) prevWordStart(s string, i int) int {
+	for i > 0 && ed.isSeparator(s[i-1]) {
+		i--
+	}
+	for i > 0 && !ed.isSeparator(s[i-1]) {
+		i--
+	}
+	return i
+}
+
+// Start of the word after index i of s: skip the rest of the word at i,
+// then the separators after it.
+func (ed *Editor) nextWordStart(s string, i int) int {
+	for i < len(s) && !ed.isSeparator(s[i]) {
+		i++
+	}
+	for i < len(s) && ed.isSeparator(s[i]) {
+		i++
+	}
+	return i
+}
+
+// Move the cursor to the start of the previous word, from the start of a
+// line to the end of the one above.
+func (ed *Editor) wordLeft() {
+	if ed.numRows() == 0 {
+		return
+	}
+	if ed.cx == 0 {
+		if ed.cy > 0 {
+			ed.cy = ed.foldHeader(ed.cy - 1)
+			ed.cx = len(ed.row(ed.cy).chars)
+		}
+		return
+	}
+	ed.cx = ed.prevWordStart(ed.row(ed.cy).chars, ed.cx)
+}
+
+// Move the cursor to the start of the next word, from the end of a line to
+// the start of the one below.
+func (ed *Editor) wordRight() {
+	if ed.numRows() == 0 {
+		return
+	}
+	chars := ed.row(ed.cy).chars
+	if ed.cx >= len(chars) {
+		if ed.foldEnd(ed.cy) < ed.numRows()-1 {
+			ed.cy, ed.cx = ed.foldEnd(ed.cy)+1, 0
+		}
+		return
+	}
+	ed.cx = ed.nextWordStart(chars, ed.cx)
+}
+
+// Delete from the cursor back to the start of the previous word, like Ctrl-W
+// in a shell. Stops at the start of the line rather than joining lines.
+func (ed *Editor) delWordBack() {
+	if !ed.checkWritable() || ed.numRows() == 0 || ed.cx == 0 {
+		return
+	}
+	start := ed.prevWordStart(ed.rows[ed.cy].chars, ed.cx)
+	ed.rowDelChars(ed.cy, start, ed.cx)
+	ed.cx = start
+	ed.clampCursor()
+}
+
+// Count the words in s, runs of non-separators, and the characters in it as
+// UTF-8 runes.
+func (ed *Editor) countWords(s string) (words, chars int) {
+	inWord := false
+	for i := 0; i < len(s); i++ {
+		sep := ed.isSeparator(s[i])
+		if !sep && !inWord {
+			words++
+		}
+		inWord = !sep
+	}
+	return words, utf8.RuneCountInString(s)
+}
+
+// Report lines, words and characters in the buffer like wc. Characters
+// include line breaks, as in wc -m.
+func (ed *Editor) wordCount() {
+	lines, words, chars := ed.numRows(), 0, 0
+	if ed.lazy != nil {
+		// Scan the file itself rather than paging every row through the
+		// row cache.
+		r := bufio.NewReader(io.NewSectionReader(ed.lazy.f, 0, ed.lazy.size))
+		for i := 0; ; i++ {
+			if ed.scanCancelled(i) {
+				return
+			}
+			line, err := r.ReadString('\n')
+			w, c := ed.countWords(line)
+			words, chars = words+w, chars+c
+			if err != nil {
+				break
+			}
+		}
+	} else {
+		for i := range ed.rows {
+			w, c := ed.countWords(ed.rows[i].chars)
+			words, chars = words+w, chars+c
+			if i < len(ed.rows)-1 || !ed.noEOL || ed.cfg.finalNewline {
+				chars++
+				if ed.rows[i].crlf {
+					chars++
+				}
+			}
+		}
+	}
+	ed.setStatus("%d lines, %d words, %d characters", lines, words, chars)
+}
+
+// Replace the word under the cursor throughout the buffer with one typed at
+// a prompt, e.g. to rename an identifier. It matches as a search does, any
+// case with ignorecase on and, with wholeword on, only as a whole word. Each
+// match is shown and asked about: y replaces it, n skips it, a replaces it
+// and all the rest, q stops. Undo takes back one replacement at a time.
+func (ed *Editor) replaceWord() {
+	if !ed.checkWritable() || ed.numRows() == 0 {
+		return
+	}
+	word := ed.wordAt(ed.rows[ed.cy].chars, ed.cx)
+	if word == "" {
+		ed.setStatus("No word under the cursor")
+		return
+	}
+	with, ok := ed.prompt(fmt.Sprintf("Replace %s with: ", word), nil)
+	if !ok {
+		return
+	}
+	// A quoted word always compiles.
+	re, _ := ed.compileSearch(regexp.QuoteMeta(word))
+	words := ed.cfg.wholeWord
+	all, n := false, 0
+	for y := 0; y < len(ed.rows); y++ {
+		for x := 0; ; {
+			chars := ed.rows[y].chars
+			start, end := -1, -1
+			ed.eachMatchOf(re, words, chars, func(s, e int) {
+				if start < 0 && s >= x {
+					start, end = s, e
+				}
+			})
+			if start < 0 {
+				break
+			}
+			if !all {
+				ed.cy, ed.cx = y, start
+				// Counted again each time, matches go as they're replaced.
+				count := ""
+				if i, total, ok := ed.countMatches(re, words, y, start); ok {
+					count = fmt.Sprintf(" [%d/%d]", i, total)
+				}
+				answer, ok := ed.prompt(fmt.Sprintf("Replace with %s?%s (y/n/a/q) ", with, count), nil)
+				switch {
+				case !ok || answer == "q":
+					ed.setStatus("Replaced %d of %s", n, word)
+					return
+				case answer == "a":
+					all = true
+				case answer != "y":
+					x = end
+					continue
+				}
+			}
+			// Each replacement is undone on its own, back at its match.
+			ed.cy, ed.cx = y, start
+			ed.breakUndo()
+			ed.rowSetChars(y, chars[:start]+with+chars[end:])
+			ed.breakUndo()
+			n++
+			x = start + len(with)
+		}
+	}
+	ed.setStatus("Replaced %d of %s", n, word)
+}
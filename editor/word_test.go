@@ -0,0 +1,50 @@
+package editor
+
+import (
+	"reflect"
+	"testing"
+)
+
+const ctrlRight = "\x1b[1;5C"
+
+func TestWordSeparators(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		settings []string
+		// Where Ctrl-Right stops along the line, and what Ctrl-W at the
+		// end of a line of foo-bar_baz leaves.
+		stops []int
+		ctrlW string
+	}{
+		{"default", "a.txt", nil, []int{4, 12, 15}, "foo-"},
+		{"dash in words", "a.txt", []string{"separators=,."}, []int{12, 15}, ""},
+		{"underscore a separator", "a.txt", []string{"separators=-_"}, []int{4, 8, 12, 15}, "foo-bar_"},
+		{"per filetype", "a.go", []string{"ftseparators=go:_"}, []int{8, 12, 15}, "foo-bar_"},
+		{"other filetypes keep the default", "a.txt", []string{"ftseparators=go:_"}, []int{4, 12, 15}, "foo-"},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		for _, s := range tt.settings {
+			if err := cfg.set(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		path := writeTemp(t, tt.file, "foo-bar_baz qux\n")
+		keys := ""
+		var stops []int
+		for range tt.stops {
+			keys += ctrlRight
+			ed, _ := runKeys(t, cfg, path, keys)
+			stops = append(stops, ed.cx)
+		}
+		if !reflect.DeepEqual(stops, tt.stops) {
+			t.Errorf("%s: Ctrl-Right stops at %v, want %v", tt.name, stops, tt.stops)
+		}
+		// Ctrl-W from the end of the line.
+		ed, _ := runKeys(t, cfg, writeTemp(t, tt.file, "foo-bar_baz\n"), "\x1b[F\x17")
+		if got := ed.row(0).chars; got != tt.ctrlW {
+			t.Errorf("%s: Ctrl-W left %q, want %q", tt.name, got, tt.ctrlW)
+		}
+	}
+}
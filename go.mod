@@ -2,4 +2,7 @@ module current/niso
 
 go 1.14
 
-require golang.org/x/term v0.0.0-20201210144234-2321bbc49cbf // indirect
+require (
+	golang.org/x/sys v0.0.0-20201119102817-f84b799fce68
+	golang.org/x/term v0.0.0-20201210144234-2321bbc49cbf
+)